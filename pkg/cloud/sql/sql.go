@@ -131,7 +131,7 @@ func (l *LocalSqlServer) start() error {
 	}
 
 	// Start the local database container
-	dockerClient, err := docker.New()
+	dockerClient, err := docker.Default()
 	if err != nil {
 		return err
 	}
@@ -147,6 +147,7 @@ func (l *LocalSqlServer) start() error {
 	volume, err := dockerClient.VolumeCreate(context.Background(), volume.CreateOptions{
 		Driver: "local",
 		Name:   fmt.Sprintf("%s-local-sql", l.projectName),
+		Labels: map[string]string{docker.StackLabel: l.projectName},
 	})
 	if err != nil {
 		return err
@@ -169,6 +170,7 @@ func (l *LocalSqlServer) start() error {
 			"POSTGRES_PASSWORD=localsecret",
 			"PGDATA=/var/lib/postgresql/data/pgdata",
 		},
+		Labels: map[string]string{docker.StackLabel: l.projectName},
 	}, &container.HostConfig{
 		AutoRemove: true,
 		Mounts: []mount.Mount{
@@ -194,7 +196,7 @@ func (l *LocalSqlServer) start() error {
 }
 
 func (l *LocalSqlServer) Stop() error {
-	dockerClient, err := docker.New()
+	dockerClient, err := docker.Default()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,154 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// apiLogTracerProvider logs every request made to the docker daemon, along with its arguments, result and duration.
+// It hooks into the tracing of the docker client rather than wrapping its transport, which the client relies on
+// for the daemon's scheme and TLS configuration.
+type apiLogTracerProvider struct {
+	noop.TracerProvider
+	logger *log.Logger
+}
+
+func (p *apiLogTracerProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &apiLogTracer{logger: p.logger}
+}
+
+type apiLogTracer struct {
+	noop.Tracer
+	logger *log.Logger
+}
+
+// Start is called with the method and path of each request, e.g. "GET /v1.44/containers/json"
+func (t *apiLogTracer) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &apiLogSpan{logger: t.logger, call: name, start: time.Now()}
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// apiLogSpan records a single daemon API call, logging it when it starts and once its response has been read
+type apiLogSpan struct {
+	noop.Span
+	logger *log.Logger
+	call   string
+	start  time.Time
+
+	lock   sync.Mutex
+	args   string
+	status int64
+	err    string
+	ended  bool
+}
+
+func (s *apiLogSpan) SetAttributes(attrs ...attribute.KeyValue) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, attr := range attrs {
+		switch attr.Key {
+		case "http.url", "url.full":
+			if u, err := url.Parse(attr.Value.AsString()); err == nil {
+				s.args = queryArgsSummary(u.Query())
+				s.logger.Printf("docker api: %s%s", s.call, s.args)
+			}
+		case "http.status_code", "http.response.status_code":
+			s.status = attr.Value.AsInt64()
+		}
+	}
+}
+
+func (s *apiLogSpan) SetStatus(code codes.Code, description string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if code == codes.Error && description != "" {
+		s.err = description
+	}
+}
+
+// End is called once the response body has been read or closed, or when the request fails
+func (s *apiLogSpan) End(...trace.SpanEndOption) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	s.ended = true
+	duration := time.Since(s.start)
+
+	switch {
+	case s.err != "" && s.status == 0:
+		s.logger.Printf("docker api: %s%s failed after %s: %s", s.call, s.args, duration, s.err)
+	case s.err != "":
+		s.logger.Printf("docker api: %s%s %d failed after %s: %s", s.call, s.args, s.status, duration, s.err)
+	default:
+		s.logger.Printf("docker api: %s%s %d (%s)", s.call, s.args, s.status, duration)
+	}
+}
+
+// redactedQueryArgs are query arguments that may carry secrets, their values are never logged.
+// Registry credentials are sent in headers, which are never logged either.
+var redactedQueryArgs = []string{"buildargs"}
+
+// maxQueryArgLength limits the length of each logged query argument value
+const maxQueryArgLength = 80
+
+// queryArgsSummary summarizes the query arguments of a request for logging, e.g. " all=1 filters={...}"
+func queryArgsSummary(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	summary := strings.Builder{}
+
+	for _, name := range names {
+		value := strings.Join(query[name], ",")
+
+		switch {
+		case slices.Contains(redactedQueryArgs, name):
+			value = "<redacted>"
+		case len(value) > maxQueryArgLength:
+			value = value[:maxQueryArgLength] + "..."
+		}
+
+		fmt.Fprintf(&summary, " %s=%s", name, value)
+	}
+
+	return summary.String()
+}
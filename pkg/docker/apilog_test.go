@@ -0,0 +1,149 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 to dir as the docker CLI's ca.pem, cert.pem and key.pem
+func writeTestCertificate(t *testing.T, dir string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "docker"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	for name, contents := range map[string][]byte{"ca.pem": certPem, "cert.pem": certPem, "key.pem": keyPem} {
+		if err := os.WriteFile(filepath.Join(dir, name), contents, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cert, err := tls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestAPILoggerKeepsDaemonTLS(t *testing.T) {
+	certDir := t.TempDir()
+	cert := writeTestCertificate(t, certDir)
+
+	daemon := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/containers/json") {
+			_ = json.NewEncoder(w).Encode([]types.Container{})
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	daemon.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	daemon.StartTLS()
+	defer daemon.Close()
+
+	t.Setenv("DOCKER_HOST", "tcp://"+daemon.Listener.Addr().String())
+	t.Setenv("DOCKER_TLS_VERIFY", "1")
+	t.Setenv("DOCKER_CERT_PATH", certDir)
+
+	logs := &bytes.Buffer{}
+
+	dockerClient, err := newClient(&clientOptions{apiLogger: log.New(logs, "", 0), apiVersion: "1.44"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dockerClient.Close()
+
+	_, err = dockerClient.ContainerList(context.Background(), container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "io.nitric.stack=test")),
+	})
+	if err != nil {
+		t.Fatalf("expected the TLS daemon to be reached with the API logger enabled, got %v", err)
+	}
+
+	for _, want := range []string{"docker api: GET /v1.44/containers/json all=1 filters=", "io.nitric.stack=test", " 200 ("} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected the log to contain %q, got:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestQueryArgsSummaryRedactsBuildArgs(t *testing.T) {
+	summary := queryArgsSummary(map[string][]string{
+		"buildargs": {`{"API_TOKEN":"secret"}`},
+		"t":         {"api:latest"},
+	})
+
+	if strings.Contains(summary, "secret") {
+		t.Errorf("expected build args to be redacted, got %s", summary)
+	}
+
+	if summary != " buildargs=<redacted> t=api:latest" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+}
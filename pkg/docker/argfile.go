@@ -0,0 +1,117 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultBuildArgsFile is the conventional name of a build args file
+const DefaultBuildArgsFile = ".build-args"
+
+// parseBuildArgs reads KEY=value build args, one per line. Blank lines and lines starting with # are ignored,
+// values may contain = and may be wrapped in matching quotes to preserve surrounding whitespace. A KEY without a value takes its value from the environment,
+// like `docker build --build-arg KEY`, and is skipped if unset.
+func parseBuildArgs(r io.Reader) (map[string]string, error) {
+	args := map[string]string{}
+
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("invalid build arg on line %d: %s", lineNumber, line)
+		}
+
+		if !hasValue {
+			if envValue, ok := os.LookupEnv(key); ok {
+				args[key] = envValue
+			}
+
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		args[key] = value
+	}
+
+	return args, scanner.Err()
+}
+
+// LoadBuildArgsFile reads build args from a KEY=value file, e.g. .build-args
+func LoadBuildArgsFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	args, err := parseBuildArgs(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read build args file %s: %w", path, err)
+	}
+
+	return args, nil
+}
+
+// WithBuildArgsFile - loads build args from a KEY=value file (see LoadBuildArgsFile), args provided with WithBuildArgs take precedence
+func WithBuildArgsFile(path string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.argsFile = path
+	}
+}
+
+// mergeArgsFile merges the args of the build args file under the explicitly provided args, it's safe to call more than once
+func (o *dockerBuildOptions) mergeArgsFile() error {
+	if o.argsFile == "" {
+		return nil
+	}
+
+	fileArgs, err := LoadBuildArgsFile(o.argsFile)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range o.args {
+		fileArgs[k] = v
+	}
+
+	o.args = fileArgs
+	o.argsFile = ""
+
+	return nil
+}
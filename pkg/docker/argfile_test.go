@@ -0,0 +1,83 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseBuildArgs(t *testing.T) {
+	t.Setenv("FROM_ENV", "env-value")
+
+	contents := `# shared build args
+
+NODE_VERSION=20
+CONNECTION=postgres://user:pass@db:5432/app?sslmode=disable&x=y
+  GREETING = "hello world"
+EMPTY=
+FROM_ENV
+UNSET_IN_ENV
+`
+
+	got, err := parseBuildArgs(strings.NewReader(contents))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"NODE_VERSION": "20",
+		"CONNECTION":   "postgres://user:pass@db:5432/app?sslmode=disable&x=y",
+		"GREETING":     "hello world",
+		"EMPTY":        "",
+		"FROM_ENV":     "env-value",
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("build args mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := parseBuildArgs(strings.NewReader("INVALID KEY=value\n")); err == nil {
+		t.Error("expected an error for a key containing whitespace")
+	}
+}
+
+func TestMergeArgsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultBuildArgsFile)
+
+	if err := os.WriteFile(path, []byte("PROVIDER=from-file\nREGION=us-east-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := defaultBuildOptions()
+	WithBuildArgs(map[string]string{"PROVIDER": "aws"})(opts)
+	WithBuildArgsFile(path)(opts)
+
+	if err := opts.mergeArgsFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"PROVIDER": "aws", "REGION": "us-east-1"}
+
+	if diff := cmp.Diff(want, opts.args); diff != "" {
+		t.Errorf("merged args mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// expandDockerfileArgs substitutes $VAR, ${VAR}, ${VAR:-default} and ${VAR:+alternate} references with arg values.
+// Returns an error naming the first referenced arg without a value.
+func expandDockerfileArgs(s string, args map[string]string) (string, error) {
+	var missing string
+
+	expanded := os.Expand(s, func(ref string) string {
+		if name, def, found := strings.Cut(ref, ":-"); found {
+			if value := args[name]; value != "" {
+				return value
+			}
+
+			return def
+		}
+
+		if name, alt, found := strings.Cut(ref, ":+"); found {
+			if args[name] != "" {
+				return alt
+			}
+
+			return ""
+		}
+
+		value, ok := args[ref]
+		if !ok && missing == "" {
+			missing = ref
+		}
+
+		return value
+	})
+
+	if missing != "" {
+		return "", fmt.Errorf("no value for build arg %s", missing)
+	}
+
+	return expanded, nil
+}
+
+// dockerfileInstructions reads the instructions of a dockerfile, joining continuation lines and dropping comments
+func dockerfileInstructions(r io.Reader) ([]string, error) {
+	instructions := []string{}
+	current := ""
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if continued, found := strings.CutSuffix(line, "\\"); found {
+			current += continued + " "
+			continue
+		}
+
+		current += line
+
+		if strings.TrimSpace(current) != "" {
+			instructions = append(instructions, strings.TrimSpace(current))
+		}
+
+		current = ""
+	}
+
+	if strings.TrimSpace(current) != "" {
+		instructions = append(instructions, strings.TrimSpace(current))
+	}
+
+	return instructions, scanner.Err()
+}
+
+// dockerfileBaseImages returns the distinct external images referenced by the FROM instructions of a dockerfile.
+// ARGs declared before the first FROM are substituted, using the build args in place of their defaults.
+// References to earlier build stages and scratch are excluded, as they aren't pulled.
+func dockerfileBaseImages(r io.Reader, buildArgs map[string]string) ([]string, error) {
+	instructions, err := dockerfileInstructions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// only ARGs declared before the first FROM are in scope for FROM instructions
+	globalArgs := map[string]string{}
+	seenFrom := false
+	stages := []string{}
+	images := []string{}
+
+	for _, instruction := range instructions {
+		fields := strings.Fields(instruction)
+		keyword := strings.ToUpper(fields[0])
+
+		switch {
+		case keyword == "ARG" && !seenFrom:
+			for _, arg := range fields[1:] {
+				name, def, _ := strings.Cut(arg, "=")
+
+				if value, ok := buildArgs[name]; ok {
+					globalArgs[name] = value
+				} else {
+					globalArgs[name] = strings.Trim(def, `"'`)
+				}
+			}
+		case keyword == "FROM":
+			seenFrom = true
+
+			// drop flags, e.g. --platform=linux/amd64
+			operands := lo.Filter(fields[1:], func(f string, _ int) bool {
+				return !strings.HasPrefix(f, "--")
+			})
+
+			if len(operands) == 0 {
+				return nil, fmt.Errorf("invalid instruction: %s", instruction)
+			}
+
+			// stage names are case insensitive and can't be substituted with args, so compare the raw operand
+			isStage := lo.Contains(stages, strings.ToLower(operands[0]))
+
+			if len(operands) == 3 && strings.EqualFold(operands[1], "AS") {
+				stages = append(stages, strings.ToLower(operands[2]))
+			}
+
+			if isStage {
+				continue
+			}
+
+			image, err := expandDockerfileArgs(operands[0], globalArgs)
+			if err != nil {
+				return nil, fmt.Errorf("unable to resolve image of %s: %w", instruction, err)
+			}
+
+			if image != "scratch" && !lo.Contains(images, image) {
+				images = append(images, image)
+			}
+		}
+	}
+
+	return images, nil
+}
+
+// PullDockerfileBases pulls every base image referenced by the FROM instructions of a dockerfile, so it can later be built offline.
+// ARG parameterized images are resolved with the build args, falling back to the ARG defaults. Build stage references aren't pulled.
+// The images are pulled concurrently, see PullImages. Returns the pulled images, in the order they're referenced.
+func (d *Docker) PullDockerfileBases(dockerfilePath string, auth *RegistryAuth, buildArgs map[string]string) ([]string, error) {
+	f, err := os.Open(dockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	images, err := dockerfileBaseImages(f, buildArgs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read base images of %s: %w", dockerfilePath, err)
+	}
+
+	return d.PullImages(images, PullImagesOptions{Auth: auth})
+}
@@ -0,0 +1,86 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDockerfileBaseImages(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+		buildArgs  map[string]string
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name: "multi-stage with stage references",
+			dockerfile: `# syntax=docker/dockerfile:1
+FROM --platform=linux/amd64 golang:1.22 AS Build
+RUN go build -o /app
+
+FROM build AS test
+RUN go test ./...
+
+FROM gcr.io/distroless/static \
+  AS runtime
+COPY --from=build /app /app
+
+FROM RUNTIME
+`,
+			want: []string{"golang:1.22", "gcr.io/distroless/static"},
+		},
+		{
+			name: "arg defaults and overrides",
+			dockerfile: `ARG BASE=node
+ARG VERSION=18
+FROM ${BASE}:${VERSION}-alpine
+ARG VERSION=20
+FROM $BASE:${TAG:-latest}
+`,
+			buildArgs: map[string]string{"VERSION": "22"},
+			want:      []string{"node:22-alpine", "node:latest"},
+		},
+		{
+			name:       "scratch and duplicates",
+			dockerfile: "FROM alpine AS a\nFROM alpine AS b\nFROM scratch\n",
+			want:       []string{"alpine"},
+		},
+		{
+			name:       "unresolved arg",
+			dockerfile: "FROM python:${PYTHON_VERSION}\n",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dockerfileBaseImages(strings.NewReader(tt.dockerfile), tt.buildArgs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dockerfileBaseImages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); !tt.wantErr && diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// BuildKit plain progress step vertex, e.g. "#7 [build 3/10] RUN make"
+	buildkitStepVertexPattern = regexp.MustCompile(`^(#\d+) \[(?:[^\]]* )?\d+/\d+\] (\S+)`)
+	// BuildKit plain progress vertex completion, e.g. "#7 CACHED" or "#7 DONE 1.2s"
+	buildkitVertexDonePattern = regexp.MustCompile(`^(#\d+) (CACHED|DONE)\b`)
+)
+
+// cacheStatsWriter counts the build steps served from the build cache (hits) and those executed (misses) from build output.
+// FROM steps are excluded, as pulling a base image isn't cacheable.
+type cacheStatsWriter struct {
+	lineWriter
+	hits   int
+	misses int
+	// BuildKit step vertices that haven't completed
+	pendingVertices map[string]bool
+	// whether the current classic builder step is awaiting its result
+	classicStepPending bool
+}
+
+func newCacheStatsWriter() *cacheStatsWriter {
+	w := &cacheStatsWriter{pendingVertices: map[string]bool{}}
+	w.onLine = w.line
+
+	return w
+}
+
+// completeClassicStep counts the pending classic builder step as executed
+func (w *cacheStatsWriter) completeClassicStep() {
+	if w.classicStepPending {
+		w.misses++
+		w.classicStepPending = false
+	}
+}
+
+// line is called with the lineWriter lock held
+func (w *cacheStatsWriter) line(line []byte) {
+	trimmed := strings.TrimSpace(string(line))
+
+	if matches := buildkitStepVertexPattern.FindStringSubmatch(trimmed); matches != nil {
+		if !strings.EqualFold(matches[2], "FROM") {
+			w.pendingVertices[matches[1]] = true
+		}
+
+		return
+	}
+
+	if matches := buildkitVertexDonePattern.FindStringSubmatch(trimmed); matches != nil {
+		if w.pendingVertices[matches[1]] {
+			delete(w.pendingVertices, matches[1])
+
+			if matches[2] == "CACHED" {
+				w.hits++
+			} else {
+				w.misses++
+			}
+		}
+
+		return
+	}
+
+	// classic builder steps are followed by " ---> Using cache" when cached, otherwise by their output
+	if classicStepPattern.MatchString(trimmed) {
+		w.completeClassicStep()
+
+		_, instruction, _ := strings.Cut(trimmed, " : ")
+		w.classicStepPending = !strings.HasPrefix(strings.ToUpper(instruction), "FROM ")
+
+		return
+	}
+
+	if w.classicStepPending && trimmed == "---> Using cache" {
+		w.hits++
+		w.classicStepPending = false
+	}
+}
+
+// stats returns the cache hits and misses of the build, once all of its output has been written
+func (w *cacheStatsWriter) stats() (hits, misses int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.completeClassicStep()
+
+	return w.hits, w.misses
+}
@@ -0,0 +1,81 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"testing"
+)
+
+func TestCacheStatsWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		wantHits   int
+		wantMisses int
+	}{
+		{
+			name: "buildkit",
+			output: `#4 [1/4] FROM docker.io/library/golang:1.22@sha256:abc
+#4 DONE 0.0s
+
+#5 [2/4] COPY go.mod go.sum ./
+#5 CACHED
+
+#6 [3/4] RUN go mod download
+#6 CACHED
+
+#7 [4/4] RUN go build -o /app
+#7 0.512 compiling
+#7 DONE 12.3s
+`,
+			wantHits:   2,
+			wantMisses: 1,
+		},
+		{
+			name: "classic builder",
+			output: `Step 1/4 : FROM golang:1.22
+ ---> 05455a08881e
+Step 2/4 : COPY go.mod go.sum ./
+ ---> Using cache
+ ---> 1b2c3d4e5f6a
+Step 3/4 : RUN go mod download
+ ---> Running in 0a1b2c3d4e5f
+Step 4/4 : RUN go build -o /app
+ ---> Running in 6f5e4d3c2b1a
+Successfully built 9a8b7c6d5e4f
+`,
+			wantHits:   1,
+			wantMisses: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newCacheStatsWriter()
+
+			if _, err := w.Write([]byte(tt.output)); err != nil {
+				t.Fatal(err)
+			}
+
+			hits, misses := w.stats()
+
+			if hits != tt.wantHits || misses != tt.wantMisses {
+				t.Errorf("stats() = %d hits, %d misses, want %d hits, %d misses", hits, misses, tt.wantHits, tt.wantMisses)
+			}
+		})
+	}
+}
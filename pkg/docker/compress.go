@@ -0,0 +1,92 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/url"
+)
+
+// ContextCompression controls whether build context archives are gzip compressed before being sent to the daemon
+type ContextCompression int
+
+const (
+	// ContextCompressionAuto compresses the context only when the daemon is remote (e.g. a tcp:// or ssh:// DOCKER_HOST)
+	ContextCompressionAuto ContextCompression = iota
+	ContextCompressionAlways
+	ContextCompressionNever
+)
+
+// WithContextCompression - controls gzip compression of build context archives (see BuildFromArchive), which speeds up builds
+// against a remote daemon. Defaults to ContextCompressionAuto, as compressing for a local socket is pure overhead.
+// Contexts of local directories are transferred incrementally by BuildKit and aren't affected.
+func WithContextCompression(compression ContextCompression) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.contextCompression = compression
+	}
+}
+
+// isRemoteDaemon reports whether the daemon is reached over the network rather than a local socket or named pipe
+func isRemoteDaemon(host string) bool {
+	u, err := url.Parse(host)
+	if err != nil {
+		return false
+	}
+
+	return u.Scheme != "unix" && u.Scheme != "npipe"
+}
+
+// compressedMagic are the leading bytes of the compression formats the daemon detects in a context archive
+var compressedMagic = [][]byte{
+	{0x1f, 0x8b},                   // gzip
+	{0x42, 0x5a, 0x68},             // bzip2
+	{0xfd, 0x37, 0x7a, 0x58, 0x5a}, // xz
+	{0x28, 0xb5, 0x2f, 0xfd},       // zstd
+}
+
+// gzipArchive compresses an archive as it's read, archives that are already compressed are returned as is.
+// Closing the returned reader stops the compression if the archive hasn't been read in full.
+func gzipArchive(archive io.Reader) io.ReadCloser {
+	buffered := bufio.NewReader(archive)
+
+	// a short archive is passed through, the daemon reports any error
+	header, _ := buffered.Peek(5)
+
+	for _, magic := range compressedMagic {
+		if bytes.HasPrefix(header, magic) {
+			return io.NopCloser(buffered)
+		}
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		gz := gzip.NewWriter(writer)
+
+		_, err := io.Copy(gz, buffered)
+		if err == nil {
+			err = gz.Close()
+		}
+
+		writer.CloseWithError(err)
+	}()
+
+	return reader
+}
@@ -0,0 +1,495 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+)
+
+// ErrNotFound is returned when a container or image doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// StackLabel is the container label identifying the nitric stack a container belongs to
+const StackLabel = "io.nitric.stack"
+
+// NamespaceLabel is the container label recording the name prefix of the client that created the container
+const NamespaceLabel = "io.nitric.namespace"
+
+// SessionLabel is the container label recording the session ID of the run that created the container
+const SessionLabel = "io.nitric.session"
+
+// labelFilters returns filters matching every provided label, limited to the client's namespace when it has a name prefix
+func (d *Docker) labelFilters(labels map[string]string) filters.Args {
+	args := filters.NewArgs()
+
+	for name, value := range labels {
+		args.Add("label", fmt.Sprintf("%s=%s", name, value))
+	}
+
+	if d.namePrefix != "" {
+		args.Add("label", fmt.Sprintf("%s=%s", NamespaceLabel, d.namePrefix))
+	}
+
+	return args
+}
+
+// clientLabels adds the labels identifying the client's namespace and session to the labels of a created container, network or volume,
+// so they can be found by label later
+func (d *Docker) clientLabels(labels map[string]string) map[string]string {
+	if d.namePrefix == "" && d.sessionID == "" {
+		return labels
+	}
+
+	merged := maps.Clone(labels)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+
+	if d.namePrefix != "" {
+		merged[NamespaceLabel] = d.namePrefix
+	}
+
+	if d.sessionID != "" {
+		merged[SessionLabel] = d.sessionID
+	}
+
+	return merged
+}
+
+// listByLabel returns all containers (running or not) matching every provided label
+func (d *Docker) listByLabel(labels map[string]string) ([]types.Container, error) {
+	opts := container.ListOptions{
+		All:     true,
+		Filters: d.labelFilters(labels),
+	}
+
+	return d.Client.ContainerList(context.Background(), opts)
+}
+
+// StartByLabel starts all containers matching the provided labels.
+// Failures are collected and returned together, after every container has been attempted.
+func (d *Docker) StartByLabel(labels map[string]string) error {
+	res, err := d.listByLabel(labels)
+	if err != nil {
+		return err
+	}
+
+	startErrors := []error{}
+
+	for _, con := range res {
+		err := d.Client.ContainerStart(context.Background(), con.ID, container.StartOptions{})
+		if err != nil {
+			startErrors = append(startErrors, fmt.Errorf("unable to start container %s: %w", con.ID, err))
+		}
+	}
+
+	return errors.Join(startErrors...)
+}
+
+// StopTimeoutSeconds converts a stop timeout to the whole seconds docker accepts, rounding up so a sub-second timeout
+// still gives the container time to exit rather than becoming 0, which kills it immediately
+func StopTimeoutSeconds(timeout time.Duration) int {
+	return int(math.Ceil(timeout.Seconds()))
+}
+
+// StopByLabel stops all containers matching the provided labels.
+// A nil timeout uses the container's configured stop timeout, sub-second timeouts are rounded up to whole seconds.
+// Failures are collected and returned together, after every container has been attempted.
+func (d *Docker) StopByLabel(labels map[string]string, timeout *time.Duration) error {
+	res, err := d.listByLabel(labels)
+	if err != nil {
+		return err
+	}
+
+	opts := container.StopOptions{}
+
+	if timeout != nil {
+		seconds := StopTimeoutSeconds(*timeout)
+		opts.Timeout = &seconds
+	}
+
+	stopErrors := []error{}
+
+	for _, con := range res {
+		err := d.Client.ContainerStop(context.Background(), con.ID, opts)
+		if err != nil {
+			stopErrors = append(stopErrors, fmt.Errorf("unable to stop container %s: %w", con.ID, err))
+		}
+	}
+
+	return errors.Join(stopErrors...)
+}
+
+// Attach connects to the main process stdio of a running container, similar to `docker attach`.
+// Output is demultiplexed into stdout and stderr unless the container was started with a TTY.
+// Cancelling the context detaches from the container without stopping it.
+func (d *Docker) Attach(ctx context.Context, nameOrID string, stdin io.Reader, stdout, stderr io.Writer) error {
+	info, err := d.Client.ContainerInspect(ctx, nameOrID)
+	if err != nil {
+		return fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	resp, err := d.Client.ContainerAttach(ctx, info.ID, container.AttachOptions{
+		Stream: true,
+		Stdin:  stdin != nil,
+		Stdout: stdout != nil,
+		Stderr: stderr != nil,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to attach to container %s: %w", nameOrID, err)
+	}
+
+	defer resp.Close()
+
+	if stdout == nil {
+		stdout = io.Discard
+	}
+
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(resp.Conn, stdin)
+			_ = resp.CloseWrite()
+		}()
+	}
+
+	outputDone := make(chan error, 1)
+
+	go func() {
+		if info.Config != nil && info.Config.Tty {
+			_, err := io.Copy(stdout, resp.Reader)
+			outputDone <- err
+
+			return
+		}
+
+		_, err := stdcopy.StdCopy(stdout, stderr, resp.Reader)
+		outputDone <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		// closing the hijacked connection detaches without signalling the container
+		return nil
+	case err := <-outputDone:
+		return err
+	}
+}
+
+// ContainerStatus is a summary of a container's current state, including why it last stopped
+type ContainerStatus struct {
+	ID           string
+	Status       string
+	Running      bool
+	ExitCode     int
+	Error        string
+	OOMKilled    bool
+	RestartCount int
+}
+
+// ExitReason describes why a stopped container exited, with a hint when the cause is actionable
+func (s *ContainerStatus) ExitReason() string {
+	switch {
+	case s.OOMKilled:
+		return fmt.Sprintf("container exited (OOMKilled) with status %d — consider raising memory", s.ExitCode)
+	case s.Error != "":
+		return fmt.Sprintf("container exited with status %d: %s", s.ExitCode, s.Error)
+	default:
+		return fmt.Sprintf("container exited with status %d", s.ExitCode)
+	}
+}
+
+// Status inspects a container and returns a summary of its state, returns ErrNotFound if the container doesn't exist
+func (d *Docker) Status(nameOrID string) (*ContainerStatus, error) {
+	info, err := d.Client.ContainerInspect(context.Background(), nameOrID)
+	if client.IsErrNotFound(err) {
+		return nil, fmt.Errorf("container %s: %w", nameOrID, ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	status := &ContainerStatus{
+		ID:           info.ID,
+		RestartCount: info.RestartCount,
+	}
+
+	if info.State != nil {
+		status.Status = info.State.Status
+		status.Running = info.State.Running
+		status.ExitCode = info.State.ExitCode
+		status.Error = info.State.Error
+		status.OOMKilled = info.State.OOMKilled
+	}
+
+	return status, nil
+}
+
+// PruneStoppedContainers removes the exited and created (never started) containers of a stack, returning the removed container IDs.
+// Running and paused containers are left untouched.
+func (d *Docker) PruneStoppedContainers(stackName string) ([]string, error) {
+	opts := container.ListOptions{
+		All:     true,
+		Filters: d.labelFilters(map[string]string{StackLabel: stackName}),
+	}
+
+	opts.Filters.Add("status", "exited")
+	opts.Filters.Add("status", "created")
+
+	res, err := d.Client.ContainerList(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+
+	for _, con := range res {
+		err := d.Client.ContainerRemove(context.Background(), con.ID, container.RemoveOptions{})
+		if err != nil {
+			return removed, fmt.Errorf("unable to remove container %s: %w", con.ID, err)
+		}
+
+		removed = append(removed, con.ID)
+	}
+
+	return removed, nil
+}
+
+// ContainerExists reports whether a container with the name or ID exists, whatever its state
+func (d *Docker) ContainerExists(nameOrID string) (bool, error) {
+	_, err := d.Client.ContainerInspect(context.Background(), nameOrID)
+	if client.IsErrNotFound(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	return true, nil
+}
+
+// ExitCode returns the exit code of a container's last run, returns ErrNotFound if the container doesn't exist
+func (d *Docker) ExitCode(nameOrID string) (int, error) {
+	status, err := d.Status(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+
+	return status.ExitCode, nil
+}
+
+// LastError returns the error message the engine recorded for the container's last run (e.g. a failure to start),
+// empty when there was no error. Returns ErrNotFound if the container doesn't exist.
+func (d *Docker) LastError(nameOrID string) (string, error) {
+	status, err := d.Status(nameOrID)
+	if err != nil {
+		return "", err
+	}
+
+	return status.Error, nil
+}
+
+// GetPublishedPort returns the host IP and port a container port (e.g. "8080/tcp") is published on,
+// including ports dynamically allocated by docker
+func (d *Docker) GetPublishedPort(nameOrID, containerPort string) (string, int, error) {
+	info, err := d.Client.ContainerInspect(context.Background(), nameOrID)
+	if err != nil {
+		return "", 0, fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	port, err := nat.NewPort(nat.SplitProtoPort(containerPort))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid container port %s: %w", containerPort, err)
+	}
+
+	if info.NetworkSettings == nil || len(info.NetworkSettings.Ports[port]) == 0 {
+		return "", 0, fmt.Errorf("container port %s of %s is not published", containerPort, nameOrID)
+	}
+
+	binding := info.NetworkSettings.Ports[port][0]
+
+	hostPort, err := strconv.Atoi(binding.HostPort)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid host port %s for container %s: %w", binding.HostPort, nameOrID, err)
+	}
+
+	return binding.HostIP, hostPort, nil
+}
+
+// proxyCloseDelay is how long a connection to a published port must stay open to be accepted by the container's app,
+// rather than by docker's userland proxy, which closes it as soon as it fails to connect to the container
+const proxyCloseDelay = 200 * time.Millisecond
+
+// acceptsConnections reports whether a TCP connection to the address can be established and is kept open.
+// Docker's userland proxy accepts connections on a published host port before the container's app listens, so connections
+// closed straight away don't count. Apps that close connections without reading from them are never considered ready.
+func acceptsConnections(ctx context.Context, dialer *net.Dialer, address string) bool {
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return false
+	}
+
+	defer conn.Close()
+
+	if err := conn.SetReadDeadline(time.Now().Add(proxyCloseDelay)); err != nil {
+		return false
+	}
+
+	_, err = conn.Read(make([]byte, 1))
+
+	var netErr net.Error
+
+	return err == nil || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// portOwner returns the ID of the running container publishing a host port, empty when no container publishes it
+func (d *Docker) portOwner(ctx context.Context, port int) (string, error) {
+	res, err := d.Client.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("publish", strconv.Itoa(port))),
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to find the container publishing port %d: %w", port, err)
+	}
+
+	if len(res) == 0 {
+		return "", nil
+	}
+
+	return res[0].ID, nil
+}
+
+// WaitForPort waits until the host port accepts TCP connections, or the timeout elapses. Use GetPublishedPort to find the
+// host IP and port of a container port. The wait fails early if the container publishing the port stops running.
+//
+// The check is made from the host, connections are only considered accepted once they're kept open, see acceptsConnections.
+func (d *Docker) WaitForPort(ctx context.Context, hostIP string, port int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if hostIP == "" || hostIP == "0.0.0.0" || hostIP == "::" {
+		hostIP = "localhost"
+	}
+
+	owner, err := d.portOwner(ctx, port)
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(hostIP, strconv.Itoa(port))
+	dialer := net.Dialer{Timeout: time.Second}
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if acceptsConnections(ctx, &dialer, address) {
+			return nil
+		}
+
+		if owner != "" {
+			status, err := d.Status(owner)
+			if err != nil {
+				return err
+			}
+
+			if !status.Running {
+				return fmt.Errorf("container %s stopped before port %d accepted connections: %s", owner, port, status.ExitReason())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for %s to accept connections", timeout, address)
+		case <-ticker.C:
+		}
+	}
+}
+
+// CommitOptions configures the image created when committing a container
+type CommitOptions struct {
+	Author  string
+	Message string
+	// pause the container while committing, so the snapshot is consistent
+	Pause bool
+}
+
+// Commit snapshots a container's filesystem and configuration as a new image tagged newTag, returning the image ID
+func (d *Docker) Commit(nameOrID, newTag string, opts CommitOptions) (string, error) {
+	if _, err := reference.ParseNormalizedNamed(newTag); err != nil {
+		return "", fmt.Errorf("invalid image tag %s: %w", newTag, err)
+	}
+
+	resp, err := d.Client.ContainerCommit(context.Background(), nameOrID, container.CommitOptions{
+		Reference: newTag,
+		Author:    opts.Author,
+		Comment:   opts.Message,
+		Pause:     opts.Pause,
+	})
+	if client.IsErrNotFound(err) {
+		return "", fmt.Errorf("container %s: %w", nameOrID, ErrNotFound)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("unable to commit container %s: %w", nameOrID, err)
+	}
+
+	return resp.ID, nil
+}
+
+// FindOrphans returns the containers of a stack (running or not) that weren't created by the current session, typically left behind
+// by an earlier run that crashed, so they can be removed before they cause name or port conflicts.
+// Only containers of the client's namespace are considered. Without a session ID (see WithSessionID), every container of the stack is returned.
+func (d *Docker) FindOrphans(stackName string) ([]types.Container, error) {
+	res, err := d.Client.ContainerList(context.Background(), container.ListOptions{
+		All:     true,
+		Filters: d.labelFilters(map[string]string{StackLabel: stackName}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list containers of stack %s: %w", stackName, err)
+	}
+
+	orphans := []types.Container{}
+
+	for _, con := range res {
+		if d.sessionID == "" || con.Labels[SessionLabel] != d.sessionID {
+			orphans = append(orphans, con)
+		}
+	}
+
+	return orphans, nil
+}
@@ -0,0 +1,297 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newContainersDaemon fakes a docker daemon with a single running container labeled with the test stack
+func newContainersDaemon(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/json"):
+			fmt.Fprint(w, `[{"Id":"test-id","Names":["/test"],"Labels":{"io.nitric.stack":"test-stack"}}]`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/test-id/json"):
+			fmt.Fprint(w, `{"Id":"test-id","RestartCount":2,"State":{"Status":"running","Running":true}}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/containers/test-id/start"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+}
+
+func newTestDocker(t *testing.T, daemon *httptest.Server) *Docker {
+	dockerClient, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(daemon.URL, "http://")), client.WithVersion("1.44"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Docker{Client: dockerClient}
+}
+
+// TestDockerConcurrentUse exercises a single engine from many goroutines, run with -race to detect unsynchronized state
+func TestDockerConcurrentUse(t *testing.T) {
+	daemon := newContainersDaemon(t)
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			status, err := d.Status("test-id")
+			if err != nil {
+				t.Errorf("Status() error = %v", err)
+				return
+			}
+
+			if !status.Running || status.RestartCount != 2 {
+				t.Errorf("Status() = %+v, want running with 2 restarts", status)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			if err := d.StartByLabel(map[string]string{StackLabel: "test-stack"}); err != nil {
+				t.Errorf("StartByLabel() error = %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestRemoveByLabelRemovesEveryContainerWhenLogsFail(t *testing.T) {
+	removed := atomic.Int32{}
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/json"):
+			fmt.Fprint(w, `[{"Id":"first-id","Names":["/first"]},{"Id":"second-id","Names":["/second"]}]`)
+		case r.Method == http.MethodGet && (strings.HasSuffix(r.URL.Path, "/json") || strings.HasSuffix(r.URL.Path, "/logs")):
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message":"logs unavailable"}`)
+		case r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "/containers/"):
+			removed.Add(1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	err := d.RemoveByLabel(map[string]string{StackLabel: "test-stack"}, WithLogDir(t.TempDir()))
+	if err == nil {
+		t.Fatal("expected the failed log dumps to be reported")
+	}
+
+	if removed.Load() != 2 {
+		t.Errorf("expected both containers to be removed, %d were", removed.Load())
+	}
+}
+
+// listen accepts TCP connections on a dynamic localhost port, passing each to handle, until the test ends
+func listen(t *testing.T, handle func(conn net.Conn)) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go handle(conn)
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestWaitForPort(t *testing.T) {
+	running := atomic.Bool{}
+	running.Store(true)
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/json"):
+			fmt.Fprint(w, `[{"Id":"api-id"}]`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/api-id/json"):
+			fmt.Fprintf(w, `{"Id":"api-id","State":{"Running":%t,"ExitCode":1}}`, running.Load())
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	// an app holds connections open until the client sends a request
+	app := listen(t, func(conn net.Conn) {
+		defer conn.Close()
+		_, _ = conn.Read(make([]byte, 1))
+	})
+
+	if err := d.WaitForPort(context.Background(), "127.0.0.1", app, 5*time.Second); err != nil {
+		t.Errorf("expected the app's port to be ready, got %v", err)
+	}
+
+	// docker's userland proxy accepts connections and closes them while the app isn't listening
+	proxy := listen(t, func(conn net.Conn) {
+		conn.Close()
+	})
+
+	if err := d.WaitForPort(context.Background(), "127.0.0.1", proxy, time.Second); err == nil {
+		t.Error("expected connections closed by the proxy not to be considered ready")
+	}
+
+	running.Store(false)
+
+	start := time.Now()
+
+	err := d.WaitForPort(context.Background(), "127.0.0.1", proxy, 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "stopped") {
+		t.Errorf("expected the wait to fail once the container stopped, got %v", err)
+	}
+
+	if time.Since(start) > 2*time.Second {
+		t.Error("expected the wait to fail early once the container stopped")
+	}
+}
+
+func TestContainerCreateKeepsCallerLabels(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/containers/create") {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"Id":"api-id"}`)
+
+			return
+		}
+
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotImplemented)
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+	d.namePrefix = "ci-1"
+	d.sessionID = "session-1"
+
+	labels := map[string]string{StackLabel: "test-stack"}
+	config := &container.Config{Image: "api", Labels: labels}
+
+	if _, err := d.ContainerCreate(config, &container.HostConfig{}, nil, "api"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{StackLabel: "test-stack"}
+
+	if diff := cmp.Diff(want, labels); diff != "" {
+		t.Errorf("caller's labels were modified (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(want, config.Labels); diff != "" {
+		t.Errorf("caller's config labels were modified (-want +got):\n%s", diff)
+	}
+}
+
+func TestStopTimeoutSeconds(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    int
+	}{
+		{timeout: 0, want: 0},
+		{timeout: 500 * time.Millisecond, want: 1},
+		{timeout: time.Second, want: 1},
+		{timeout: 1500 * time.Millisecond, want: 2},
+		{timeout: 30 * time.Second, want: 30},
+	}
+
+	for _, tt := range tests {
+		if got := StopTimeoutSeconds(tt.timeout); got != tt.want {
+			t.Errorf("StopTimeoutSeconds(%s) = %d, want %d", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestStopByLabelRoundsUpSubSecondTimeouts(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/json"):
+			fmt.Fprint(w, `[{"Id":"api-id"}]`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/containers/api-id/stop"):
+			if got := r.URL.Query().Get("t"); got != "1" {
+				t.Errorf("expected a stop timeout of 1 second, got %q", got)
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	timeout := 500 * time.Millisecond
+
+	if err := d.StopByLabel(map[string]string{StackLabel: "test-stack"}, &timeout); err != nil {
+		t.Fatal(err)
+	}
+}
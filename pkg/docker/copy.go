@@ -0,0 +1,82 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// CopyBetweenContainers copies a file or directory from one container into a directory of another container.
+// The tar stream from the source container is passed straight to the destination without buffering to disk.
+func (d *Docker) CopyBetweenContainers(srcNameOrID, srcPath, dstNameOrID, dstPath string) error {
+	ctx := context.Background()
+
+	// stat the destination first, so a missing destination doesn't leave a half-read stream from the source
+	dstStat, err := d.Client.ContainerStatPath(ctx, dstNameOrID, dstPath)
+	if client.IsErrNotFound(err) {
+		return fmt.Errorf("destination path %s not found in container %s: %w", dstPath, dstNameOrID, ErrNotFound)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to stat %s in container %s: %w", dstPath, dstNameOrID, err)
+	}
+
+	if !dstStat.Mode.IsDir() {
+		return fmt.Errorf("destination path %s in container %s is not a directory", dstPath, dstNameOrID)
+	}
+
+	content, _, err := d.Client.CopyFromContainer(ctx, srcNameOrID, srcPath)
+	if client.IsErrNotFound(err) {
+		return fmt.Errorf("source path %s not found in container %s: %w", srcPath, srcNameOrID, ErrNotFound)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to copy %s from container %s: %w", srcPath, srcNameOrID, err)
+	}
+	defer content.Close()
+
+	err = d.Client.CopyToContainer(ctx, dstNameOrID, dstPath, content, types.CopyToContainerOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to copy %s from container %s to %s in container %s: %w", srcPath, srcNameOrID, dstPath, dstNameOrID, err)
+	}
+
+	return nil
+}
+
+// CopyFromArchive extracts a tar archive into a directory of a container. Large copies can be aborted by cancelling the context.
+func (d *Docker) CopyFromArchive(ctx context.Context, nameOrID, dstPath string, archive io.Reader) error {
+	err := d.Client.CopyToContainer(ctx, nameOrID, dstPath, archive, types.CopyToContainerOptions{})
+	if err == nil {
+		return nil
+	}
+
+	// a copy that completed before the context was cancelled isn't reported as cancelled
+	if ctx.Err() != nil {
+		return fmt.Errorf("copy to %s in container %s cancelled: %w", dstPath, nameOrID, ctx.Err())
+	}
+
+	if client.IsErrNotFound(err) {
+		return fmt.Errorf("destination path %s not found in container %s: %w", dstPath, nameOrID, ErrNotFound)
+	}
+
+	return fmt.Errorf("unable to copy to %s in container %s: %w", dstPath, nameOrID, err)
+}
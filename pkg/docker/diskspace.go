@@ -0,0 +1,53 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDiskSpaceUnknown is returned when the free space available to the docker daemon can't be determined,
+// e.g. when the daemon is remote or runs inside a VM (Docker Desktop)
+var ErrDiskSpaceUnknown = errors.New("unable to determine free disk space available to docker")
+
+// CheckDiskSpace reports whether the docker data root has at least requiredBytes free, along with the bytes available.
+// The data root can only be inspected when the daemon shares the host's filesystem, otherwise ErrDiskSpaceUnknown is returned.
+func (d *Docker) CheckDiskSpace(requiredBytes int64) (bool, int64, error) {
+	// a remote daemon's data root would be looked up on the local filesystem instead
+	if host := d.Client.DaemonHost(); isRemoteDaemon(host) {
+		return false, 0, fmt.Errorf("%w: the daemon at %s is remote", ErrDiskSpaceUnknown, host)
+	}
+
+	info, err := d.Client.Info(context.Background())
+	if err != nil {
+		return false, 0, fmt.Errorf("unable to retrieve docker daemon info: %w", err)
+	}
+
+	// as would the data root of a daemon running in Docker Desktop's VM
+	if isDockerDesktop(info) {
+		return false, 0, fmt.Errorf("%w: the daemon runs in the Docker Desktop VM", ErrDiskSpaceUnknown)
+	}
+
+	available, err := freeDiskSpace(info.DockerRootDir)
+	if err != nil {
+		return false, 0, errors.Join(ErrDiskSpaceUnknown, err)
+	}
+
+	return available >= requiredBytes, available, nil
+}
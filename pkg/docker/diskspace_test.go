@@ -0,0 +1,38 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckDiskSpaceSkipsRemoteDaemons(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected the remote daemon not to be inspected, got %s %s", r.Method, r.URL.Path)
+	}))
+	defer daemon.Close()
+
+	// the test daemon is reached over tcp
+	d := newTestDocker(t, daemon)
+
+	if _, _, err := d.CheckDiskSpace(1); !errors.Is(err, ErrDiskSpaceUnknown) {
+		t.Errorf("expected ErrDiskSpaceUnknown for a remote daemon, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package docker
+
+import "syscall"
+
+// freeDiskSpace returns the bytes available to unprivileged users on the filesystem containing path
+func freeDiskSpace(path string) (int64, error) {
+	stat := syscall.Statfs_t{}
+
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return int64(uint64(stat.Bavail) * uint64(stat.Bsize)), nil
+}
@@ -18,32 +18,52 @@ package docker
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	goerrors "errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/sockets"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 
 	"github.com/nitrictech/cli/pkg/view/tui"
 )
 
+// Docker is a client for the docker (or podman) engine.
+// It's safe for concurrent use by multiple goroutines, the underlying client is shared and any mutable state is synchronized.
 type Docker struct {
 	*client.Client
 	// logger ContainerLogger
+
+	// namespace applied to the names of created containers, isolating concurrent runs on a shared host
+	namePrefix string
+	// TLS configuration of registries used by the buildx builder, keyed by registry host
+	registryTLS map[string]RegistryTLS
+	// limit on the duration of each image pull, 0 is unlimited
+	pullTimeout time.Duration
+	// identifies the current run, labeling created containers
+	sessionID string
 }
 
 func VerifyDockerIsAvailable() error {
@@ -68,89 +88,804 @@ func VerifyDockerIsAvailable() error {
 	return nil
 }
 
-func New() (*Docker, error) {
+type clientOptions struct {
+	// logs every daemon API call when set
+	apiLogger   *log.Logger
+	namePrefix  string
+	registryTLS map[string]RegistryTLS
+	pullTimeout time.Duration
+	// API version used instead of negotiating with the daemon
+	apiVersion string
+	sessionID  string
+	// limit on the wait for the daemon to respond to each API call, 0 is unlimited
+	responseTimeout time.Duration
+	// interval of TCP keep-alive probes on connections to a remote daemon, 0 is the Go default
+	keepAlive time.Duration
+}
+
+type ClientOption func(*clientOptions)
+
+// WithAPILogger - logs each docker daemon API call (method, path, query arguments, status and duration) to the provided logger,
+// for debugging engine issues. Calls are logged when they start and again when their response has been read, so a stuck call
+// is visible. Headers (which carry registry auth) and build args are never logged.
+func WithAPILogger(logger *log.Logger) ClientOption {
+	return func(o *clientOptions) {
+		o.apiLogger = logger
+	}
+}
+
+// WithNamePrefix - prefixes the names of created containers (e.g. with a session ID) so concurrent runs of the same stack
+// on a shared host don't collide. Created containers are labeled with the prefix and label based listing is limited to them.
+func WithNamePrefix(prefix string) ClientOption {
+	return func(o *clientOptions) {
+		o.namePrefix = prefix
+	}
+}
+
+// WithSessionID - labels created containers with an ID identifying the current run (see SessionLabel), so containers left behind
+// by earlier runs that crashed can be found with FindOrphans
+func WithSessionID(sessionID string) ClientOption {
+	return func(o *clientOptions) {
+		o.sessionID = sessionID
+	}
+}
+
+// WithPullTimeout - limits the duration of each image pull made by the client (e.g. ImagePull and PullDockerfileBases),
+// independently of any build timeout, so a slow network is reported as a pull timeout rather than a slow build.
+// Base images pulled by the builder during a build aren't covered, as the builder pulls them itself,
+// call PullDockerfileBases before building to pull them within the timeout.
+func WithPullTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.pullTimeout = timeout
+	}
+}
+
+// WithAPIVersion - pins the docker API version used by the client, rather than negotiating the highest version supported by both
+// the client and the daemon. Creating the client fails if the daemon doesn't support the pinned version.
+func WithAPIVersion(version string) ClientOption {
+	return func(o *clientOptions) {
+		o.apiVersion = version
+	}
+}
+
+// WithResponseTimeout - limits the wait for the daemon to start responding to each API call, so one-shot reads (e.g. inspects)
+// fail fast against an unresponsive daemon. Only the response headers are timed, streamed bodies (logs, events and stats)
+// aren't cut off however long they run.
+func WithResponseTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.responseTimeout = timeout
+	}
+}
+
+// WithKeepAlive - sets the interval of TCP keep-alive probes on connections to a remote daemon (a tcp:// DOCKER_HOST),
+// so long-lived streams that are idle for a while aren't dropped by proxies or NAT. Local sockets aren't affected.
+func WithKeepAlive(interval time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.keepAlive = interval
+	}
+}
+
+// verifyAPIVersion ensures the daemon supports the client's pinned API version
+func verifyAPIVersion(dockerClient *client.Client, version string) error {
+	serverVersion, err := dockerClient.ServerVersion(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to retrieve engine version: %w", err)
+	}
+
+	if versions.GreaterThan(version, serverVersion.APIVersion) {
+		return fmt.Errorf("pinned API version %s is newer than the engine supports (maximum %s)", version, serverVersion.APIVersion)
+	}
+
+	if serverVersion.MinAPIVersion != "" && versions.LessThan(version, serverVersion.MinAPIVersion) {
+		return fmt.Errorf("pinned API version %s is older than the engine supports (minimum %s)", version, serverVersion.MinAPIVersion)
+	}
+
+	return nil
+}
+
+func newClient(opts *clientOptions) (*client.Client, error) {
+	// build the client's transport, matching the docker client's default, so the timeout and keep-alive options can be applied to it.
+	// The client's options (e.g. FromEnv) still configure it for the daemon host and TLS, as it remains the client's base transport.
+	transport := &http.Transport{}
+
+	defaultHost, err := client.ParseHostURL(client.DefaultDockerHost)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sockets.ConfigureTransport(transport, defaultHost.Scheme, defaultHost.Host); err != nil {
+		return nil, err
+	}
+
+	clientOpts := []client.Opt{
+		client.WithHTTPClient(&http.Client{Transport: transport, CheckRedirect: client.CheckRedirect}),
+		client.FromEnv,
+	}
+
+	if opts.apiVersion != "" {
+		clientOpts = append(clientOpts, client.WithVersion(opts.apiVersion))
+	} else {
+		clientOpts = append(clientOpts, client.WithAPIVersionNegotiation())
+	}
+
+	if opts.apiLogger != nil {
+		clientOpts = append(clientOpts, client.WithTraceProvider(&apiLogTracerProvider{logger: opts.apiLogger}))
+	}
+
+	dockerClient, err := client.NewClientWithOpts(clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	configureTransport(transport, dockerClient.DaemonHost(), opts)
+
+	return dockerClient, nil
+}
+
+// configureTransport applies the response timeout and keep-alive options to the client's transport, once it's configured for the daemon host
+func configureTransport(transport *http.Transport, daemonHost string, opts *clientOptions) {
+	transport.ResponseHeaderTimeout = opts.responseTimeout
+
+	if opts.keepAlive > 0 && strings.HasPrefix(daemonHost, "tcp://") {
+		// matches the dial timeout of the transport's default dialer
+		transport.DialContext = (&net.Dialer{Timeout: 32 * time.Second, KeepAlive: opts.keepAlive}).DialContext
+	}
+}
+
+func New(options ...ClientOption) (*Docker, error) {
 	if err := VerifyDockerIsAvailable(); err != nil {
 		return nil, err
 	}
 
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	opts := &clientOptions{}
+
+	for _, o := range options {
+		o(opts)
+	}
+
+	registryTLS, err := validateRegistryTLS(opts.registryTLS)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Docker{Client: dockerClient}, err
+	dockerClient, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.apiVersion != "" {
+		if err := verifyAPIVersion(dockerClient, opts.apiVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Docker{Client: dockerClient, namePrefix: opts.namePrefix, registryTLS: registryTLS, pullTimeout: opts.pullTimeout, sessionID: opts.sessionID}, err
+}
+
+var (
+	defaultLock   sync.Mutex
+	defaultDocker *Docker
+)
+
+// Default returns a client shared by the process, created with New on first use so the engine is only detected once.
+// Only a successfully created client is cached, after a failure (e.g. the engine isn't running yet) the next call tries again.
+// Use New for a client with its own options.
+func Default() (*Docker, error) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	if defaultDocker != nil {
+		return defaultDocker, nil
+	}
+
+	d, err := New()
+	if err != nil {
+		return nil, err
+	}
+
+	defaultDocker = d
+
+	return defaultDocker, nil
+}
+
+// ResetDefault discards the client cached by Default, the next call to Default detects the engine again.
+// It is intended for tests.
+func ResetDefault() {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	defaultDocker = nil
+}
+
+var builderLock = sync.Mutex{}
+
+type BuildxBuilder struct {
+	Name string
+}
+
+// Create a known nitric container builder to allow custom cache configuration
+func (d *Docker) createBuildxBuilder() (*BuildxBuilder, error) {
+	builderLock.Lock()
+	defer builderLock.Unlock() // Create a known fixed nitric builder to allow caching
+
+	builderName := "nitric"
+
+	args := []string{"buildx", "create", "--name", builderName, "--bootstrap", "--driver=docker-container", "--node", "nitric0"}
+
+	if len(d.registryTLS) > 0 {
+		configFile, err := os.CreateTemp("", "nitric-buildkitd-*.toml")
+		if err != nil {
+			return nil, err
+		}
+
+		defer os.Remove(configFile.Name())
+
+		_, err = configFile.WriteString(buildkitdConfig(d.registryTLS))
+		configFile.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, "--config", configFile.Name())
+	}
+
+	cmd := exec.Command("docker", args...)
+
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	return &BuildxBuilder{Name: builderName}, nil
+}
+
+type dockerBuildOptions struct {
+	// Whether or not to use a builder container
+	useBuilder bool
+	excludes   []string
+	logger     io.Writer
+	args       map[string]string
+	labels     map[string]string
+	// OCI annotations, set on the image manifest rather than the image config like labels
+	annotations map[string]string
+	tee         []io.Writer
+	cacheTo     string
+	cacheFrom   string
+	// minimum free disk space required before building, 0 skips the check
+	requiredDiskSpace int64
+	// resolve the dockerfile relative to the build context, rather than the working directory
+	dockerfileInContext bool
+	// always attempt to pull newer versions of base images
+	pullParent bool
+	// cancelling the context aborts the build
+	ctx context.Context
+	// raw arguments appended to the build command
+	extraArgs []string
+	// piped to the build, the tar archive of the build context when the context is "-" or the dockerfile when the dockerfile is "-"
+	stdin io.Reader
+	// receives estimated build progress percentages
+	onProgress func(percent int)
+	// build args permitted by the build, nil permits any
+	allowedArgs []string
+	// additionally tag the image with the content hash of its inputs
+	contentTag bool
+	// file of build args merged under args
+	argsFile string
+	// prefix each line of build output with the time elapsed since the build started
+	timestamps bool
+	// compression of the context archive
+	contextCompression ContextCompression
+	// suppress build output, it's only reported if the build fails
+	quiet bool
+	// provenance attestation mode (min or max), empty attaches no provenance
+	provenance string
+	// attach an SBOM attestation
+	sbom bool
+}
+
+func defaultBuildOptions() *dockerBuildOptions {
+	return &dockerBuildOptions{
+		useBuilder:  true,
+		excludes:    []string{},
+		logger:      io.Discard,
+		args:        map[string]string{},
+		labels:      map[string]string{},
+		annotations: map[string]string{},
+		ctx:         context.Background(),
+	}
+}
+
+type DockerBuildOption func(*dockerBuildOptions)
+
+func WithBuilder(builder bool) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.useBuilder = builder
+	}
+}
+
+func WithExcludes(excludes []string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.excludes = excludes
+	}
+}
+
+func WithLogger(logger io.Writer) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.logger = logger
+	}
+}
+
+// WithLogTee - copies the build output to the provided writer, in addition to the logger, e.g. to persist a build log
+func WithLogTee(w io.Writer) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.tee = append(o.tee, w)
+	}
+}
+
+// WithCacheTo - sets the build cache export, e.g. type=registry,ref=registry.example.com/app:buildcache,mode=max
+// Overrides any cache export configured through the DOCKER_BUILD_CACHE environment variables
+func WithCacheTo(cacheTo string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.cacheTo = cacheTo
+	}
+}
+
+// WithCacheFrom - sets the build cache import, e.g. type=registry,ref=registry.example.com/app:buildcache
+// Overrides any cache import configured through the DOCKER_BUILD_CACHE environment variables
+func WithCacheFrom(cacheFrom string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.cacheFrom = cacheFrom
+	}
+}
+
+// WithDiskSpaceCheck - fails the build up front when the docker data root has less than requiredBytes free.
+// The check is skipped when the free space can't be determined, e.g. for remote daemons.
+func WithDiskSpaceCheck(requiredBytes int64) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.requiredDiskSpace = requiredBytes
+	}
+}
+
+// WithDockerfileInContext - resolves the dockerfile path relative to the build context root, like `docker build -f deploy/Dockerfile .`
+// The build fails if the dockerfile doesn't exist or is outside of the build context.
+func WithDockerfileInContext() DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.dockerfileInContext = true
+	}
+}
+
+// resolveContextDockerfile returns the path of a dockerfile given relative to the build context root
+func resolveContextDockerfile(srcPath, dockerfile string) (string, error) {
+	if filepath.IsAbs(dockerfile) {
+		return "", fmt.Errorf("dockerfile %s must be relative to the build context %s", dockerfile, srcPath)
+	}
+
+	resolved := filepath.Join(srcPath, dockerfile)
+
+	relPath, err := filepath.Rel(srcPath, resolved)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("dockerfile %s is outside of the build context %s", dockerfile, srcPath)
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("dockerfile %s not found in build context %s: %w", dockerfile, srcPath, err)
+	}
+
+	if info.IsDir() {
+		return "", fmt.Errorf("dockerfile %s in build context %s is a directory", dockerfile, srcPath)
+	}
+
+	return resolved, nil
+}
+
+// WithPullParent - always checks the registry for newer versions of the base images when building.
+// Defaults to false, using local base images when present so builds can run offline.
+func WithPullParent(pull bool) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.pullParent = pull
+	}
+}
+
+// WithContext - aborts the build when the context is cancelled.
+// The build client is interrupted, which ends its BuildKit session and stops the build on the daemon. Builds using the
+// classic builder (e.g. podman) may continue running the current step daemon side after the client has exited.
+func WithContext(ctx context.Context) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithExtraArgs - appends raw arguments to the `buildx build` command, after all other arguments.
+// This is an advanced escape hatch for build features not otherwise exposed, the arguments aren't validated and
+// may not be supported by every engine (e.g. podman).
+func WithExtraArgs(args ...string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.extraArgs = append(o.extraArgs, args...)
+	}
+}
+
+// WithProgress - reports a best-effort estimate of build progress as a percentage (0-100), derived from the step numbers in the build output
+func WithProgress(onProgress func(percent int)) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.onProgress = onProgress
+	}
+}
+
+// WithTimestamps - prefixes each line of build output with the time elapsed since the build started, e.g. "[  12.3s]",
+// to help identify slow build steps. BuildKit also reports the duration of each step as it completes, e.g. "#7 DONE 8.1s".
+func WithTimestamps() DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.timestamps = true
+	}
+}
+
+// WithQuiet - suppresses build output, like `docker build -q`. If the build fails the end of the output is included in the error.
+// Writers added with WithLogTee still receive the full output.
+func WithQuiet() DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.quiet = true
+	}
+}
+
+func WithBuildArgs(args map[string]string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.args = args
+	}
+}
+
+// implicitlyAllowedBuildArgs are always permitted by WithAllowedBuildArgs, as nitric provides them to every build
+var implicitlyAllowedBuildArgs = []string{"PROVIDER"}
+
+// WithAllowedBuildArgs - fails the build if any build arg isn't in the allowlist, guarding against unexpected args leaking
+// into the image history. PROVIDER is always allowed.
+func WithAllowedBuildArgs(allowed []string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.allowedArgs = append(slices.Clone(allowed), implicitlyAllowedBuildArgs...)
+	}
+}
+
+// validateBuildArgs ensures every build arg is in the allowlist, when one is set
+func validateBuildArgs(args map[string]string, allowed []string) error {
+	if allowed == nil {
+		return nil
+	}
+
+	disallowed := []string{}
+
+	for k := range args {
+		if !slices.Contains(allowed, k) {
+			disallowed = append(disallowed, k)
+		}
+	}
+
+	if len(disallowed) > 0 {
+		sort.Strings(disallowed)
+		return fmt.Errorf("build args not in the allowlist: %s", strings.Join(disallowed, ", "))
+	}
+
+	return nil
+}
+
+func WithLabels(labels map[string]string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		for k, v := range labels {
+			o.labels[k] = v
+		}
+	}
+}
+
+// BuildResult describes a successfully built image
+type BuildResult struct {
+	// Tags applied to the built image
+	Tags []string
+	// content addressable tag applied with WithContentTag, empty otherwise
+	ContentTag string
+	// ID of the built image, e.g. sha256:...
+	ImageID string
+	// warnings reported by the builder, e.g. use of deprecated dockerfile syntax
+	Warnings []string
+	// best-effort count of the build steps served from the build cache and those executed, excluding FROM steps
+	CacheHits   int
+	CacheMisses int
+	// host of the remote daemon the image was built on and verified to be present on, empty for a local daemon
+	DaemonHost string
+}
+
+// WithContentTag - additionally tags the image <repository>:sha-<content hash>, using the repository of the primary tag.
+// The content hash covers exactly the inputs hashed by EnsureImage:
+//   - the sha256 of the dockerfile contents
+//   - each build arg, sorted by name
+//   - the slash separated path, permission bits and sha256 contents of every regular file in the build context
+//     sent to the build, in lexical order. Exclude patterns follow docker's .dockerignore rules, including ! and **
+//
+// Labels, annotations, cache settings and base image updates are not included, so identical inputs always produce the same tag.
+// Only supported when building from a local context with Build or BuildWithTags.
+func WithContentTag() DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.contentTag = true
+	}
+}
+
+// contentAddressableTag returns the content addressable tag for the repository of imageTag
+func contentAddressableTag(imageTag, hash string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageTag)
+	if err != nil {
+		return "", fmt.Errorf("invalid image tag %s: %w", imageTag, err)
+	}
+
+	tagged, err := reference.WithTag(reference.TrimNamed(named), "sha-"+hash)
+	if err != nil {
+		return "", err
+	}
+
+	return reference.FamiliarString(tagged), nil
+}
+
+// registryCacheRef returns the reference the build cache of an image is stored under in a cache repository, e.g. the cache of
+// ghcr.io/acme/api:latest is stored in <repository>:acme-api-latest, so the images of a project share one repository
+func registryCacheRef(repository, imageTag string) (string, error) {
+	repo, err := reference.ParseNormalizedNamed(repository)
+	if err != nil {
+		return "", fmt.Errorf("invalid build cache repository %s: %w", repository, err)
+	}
+
+	named, err := reference.ParseNormalizedNamed(imageTag)
+	if err != nil {
+		return "", fmt.Errorf("invalid image tag %s: %w", imageTag, err)
+	}
+
+	tag := "latest"
+	if tagged, ok := named.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	}
+
+	// the path of the image within its registry, without the implicit library/ namespace of docker hub
+	path := strings.TrimPrefix(reference.Path(named), "library/")
+
+	cacheTag := strings.NewReplacer("/", "-", "_", "-").Replace(path) + "-" + tag
+	if len(cacheTag) > 128 {
+		cacheTag = strings.TrimLeft(cacheTag[len(cacheTag)-128:], ".-")
+	}
+
+	tagged, err := reference.WithTag(reference.TrimNamed(repo), cacheTag)
+	if err != nil {
+		return "", fmt.Errorf("unable to derive build cache reference of %s: %w", imageTag, err)
+	}
+
+	return tagged.String(), nil
+}
+
+// WithAnnotations - sets OCI annotations (e.g. org.opencontainers.image.source) on the built image's manifest.
+// Unlike labels, which are stored in the image config and visible to `docker inspect`, annotations are read by
+// registry and supply-chain tooling. Both can be set on the same build. Requires BuildKit (buildx v0.12+).
+func WithAnnotations(annotations map[string]string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		for k, v := range annotations {
+			o.annotations[k] = v
+		}
+	}
+}
+
+// WithProvenance - attaches a SLSA provenance attestation to the built image's index, recording how it was built.
+// The mode is min (build timestamps and platform) or max (also the build args, dockerfile and source),
+// an empty mode attaches no provenance. Requires BuildKit through the nitric builder, see WithSBOM.
+func WithProvenance(mode string) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.provenance = mode
+	}
+}
+
+// WithSBOM - attaches an SBOM attestation, listing the packages in the built image, to the image's index.
+// Requires BuildKit through the nitric builder and a daemon using the containerd image store, which keeps attestations in the local
+// image store, the build fails otherwise (e.g. with podman or WithBuilder(false)).
+func WithSBOM(sbom bool) DockerBuildOption {
+	return func(o *dockerBuildOptions) {
+		o.sbom = sbom
+	}
+}
+
+// ErrAttestationsUnsupported is returned by builds requesting attestations without BuildKit or the containerd image store
+var ErrAttestationsUnsupported = errors.New("provenance and SBOM attestations require BuildKit (docker buildx with the nitric builder)")
+
+// attestationArgs returns the build arguments attaching the requested attestations
+func (o *dockerBuildOptions) attestationArgs() ([]string, error) {
+	args := []string{}
+
+	switch o.provenance {
+	case "":
+	case "min", "max":
+		args = append(args, fmt.Sprintf("--provenance=mode=%s", o.provenance))
+	default:
+		return nil, fmt.Errorf("invalid provenance mode %q: expected min or max", o.provenance)
+	}
+
+	if o.sbom {
+		args = append(args, "--sbom=true")
+	}
+
+	return args, nil
+}
+
+func (d *Docker) Build(dockerfile, srcPath, imageTag string, options ...DockerBuildOption) error {
+	_, err := d.BuildWithTags(dockerfile, srcPath, []string{imageTag}, options...)
+
+	return err
+}
+
+// BuildWithTags builds an image, applying all of the provided tags to it.
+// The first tag is the primary tag, used to name the build's cache.
+func (d *Docker) BuildWithTags(dockerfile, srcPath string, tags []string, options ...DockerBuildOption) (*BuildResult, error) {
+	opts := defaultBuildOptions()
+
+	for _, o := range options {
+		o(opts)
+	}
+
+	if opts.dockerfileInContext {
+		resolved, err := resolveContextDockerfile(srcPath, dockerfile)
+		if err != nil {
+			return nil, err
+		}
+
+		dockerfile = resolved
+	}
+
+	contentTag := ""
+
+	if opts.contentTag && len(tags) > 0 {
+		// hashed before the dockerignore file is written, as it may be within the build context
+		hash, err := contentHash(dockerfile, srcPath, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		contentTag, err = contentAddressableTag(tags[0], hash)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(slices.Clone(tags), contentTag)
+		opts.labels[ContentHashLabel] = hash
+	}
+
+	// write a temporary dockerignore file, removed even if it can't be written or the build fails
+	ignoreFile, err := os.Create(fmt.Sprintf("%s.dockerignore", dockerfile))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		os.Remove(ignoreFile.Name())
+	}()
+
+	_, err = ignoreFile.Write([]byte(strings.Join(opts.excludes, "\n")))
+	if err != nil {
+		ignoreFile.Close()
+		return nil, err
+	}
+
+	err = ignoreFile.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := d.build(srcPath, dockerfile, tags, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ContentTag = contentTag
+
+	return result, nil
+}
+
+// BuildFromRemoteContext builds an image from a remote build context, e.g. a git repository or tarball URL,
+// the same way as `docker build <url>`. Git URLs support the #ref:subdir fragment syntax to select a branch, tag or commit and a subdirectory.
+// The dockerfile path is relative to the root of the remote context, an empty path uses the context's Dockerfile.
+// Excludes are ignored, the remote context's own .dockerignore applies.
+func (d *Docker) BuildFromRemoteContext(contextURL, dockerfile string, tags []string, options ...DockerBuildOption) (*BuildResult, error) {
+	if !isRemoteContext(contextURL) {
+		return nil, fmt.Errorf("invalid remote build context %s: expected a git repository or http(s) URL", contextURL)
+	}
+
+	opts := defaultBuildOptions()
+
+	for _, o := range options {
+		o(opts)
+	}
+
+	return d.build(contextURL, dockerfile, tags, opts)
 }
 
-var builderLock = sync.Mutex{}
+// BuildFromArchive builds an image from a tar archive of the build context, the same way as `docker build - < context.tar`,
+// avoiding walking the filesystem when the context has already been assembled. The archive may be gzip compressed.
+// The dockerfile path is relative to the root of the archive, an empty path uses the archive's Dockerfile.
+// Excludes are ignored, the archive is sent as is.
+func (d *Docker) BuildFromArchive(contextArchive io.Reader, dockerfile string, tags []string, options ...DockerBuildOption) (*BuildResult, error) {
+	opts := defaultBuildOptions()
 
-type BuildxBuilder struct {
-	Name string
-}
+	for _, o := range options {
+		o(opts)
+	}
 
-// Create a known nitric container builder to allow custom cache configuration
-func (d *Docker) createBuildxBuilder() (*BuildxBuilder, error) {
-	builderLock.Lock()
-	defer builderLock.Unlock() // Create a known fixed nitric builder to allow caching
+	opts.stdin = contextArchive
 
-	builderName := "nitric"
+	compress := opts.contextCompression == ContextCompressionAlways ||
+		(opts.contextCompression == ContextCompressionAuto && isRemoteDaemon(d.Client.DaemonHost()))
 
-	cmd := exec.Command("docker", "buildx", "create", "--name", builderName, "--bootstrap", "--driver=docker-container", "--node", "nitric0")
+	if compress {
+		compressed := gzipArchive(contextArchive)
+		defer compressed.Close()
 
-	if err := cmd.Run(); err != nil {
-		return nil, err
+		opts.stdin = compressed
 	}
 
-	return &BuildxBuilder{Name: builderName}, nil
-}
-
-type dockerBuildOptions struct {
-	// Whether or not to use a builder container
-	useBuilder bool
-	excludes   []string
-	logger     io.Writer
-	args       map[string]string
+	return d.build("-", dockerfile, tags, opts)
 }
 
-func defaultBuildOptions() *dockerBuildOptions {
-	return &dockerBuildOptions{
-		useBuilder: true,
-		excludes:   []string{},
-		logger:     io.Discard,
-		args:       map[string]string{},
+// dockerfileSyntax returns the BuildKit frontend image set by a dockerfile's `# syntax=` parser directive, if any
+func dockerfileSyntax(dockerfile string) string {
+	f, err := os.Open(dockerfile)
+	if err != nil {
+		return ""
 	}
-}
 
-type DockerBuildOption func(*dockerBuildOptions)
+	defer f.Close()
 
-func WithBuilder(builder bool) DockerBuildOption {
-	return func(o *dockerBuildOptions) {
-		o.useBuilder = builder
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// parser directives must precede any other line, including blank lines
+		directive, found := strings.CutPrefix(line, "#")
+		if !found {
+			return ""
+		}
+
+		key, value, found := strings.Cut(directive, "=")
+		if !found {
+			return ""
+		}
+
+		if strings.EqualFold(strings.TrimSpace(key), "syntax") {
+			return strings.TrimSpace(value)
+		}
 	}
+
+	return ""
 }
 
-func WithExcludes(excludes []string) DockerBuildOption {
-	return func(o *dockerBuildOptions) {
-		o.excludes = excludes
+// isRemoteContext reports whether the build context is a URL docker can fetch the context from
+func isRemoteContext(buildContext string) bool {
+	for _, prefix := range []string{"https://", "http://", "git://", "git@", "github.com/"} {
+		if strings.HasPrefix(buildContext, prefix) {
+			return true
+		}
 	}
+
+	return false
 }
 
-func WithLogger(logger io.Writer) DockerBuildOption {
-	return func(o *dockerBuildOptions) {
-		o.logger = logger
+// build runs a buildx build of the provided context, which may be a local path or a remote URL
+func (d *Docker) build(buildContext, dockerfile string, tags []string, opts *dockerBuildOptions) (*BuildResult, error) {
+	if len(tags) == 0 {
+		return nil, errors.New("at least one image tag is required")
 	}
-}
 
-func WithBuildArgs(args map[string]string) DockerBuildOption {
-	return func(o *dockerBuildOptions) {
-		o.args = args
+	for _, tag := range tags {
+		if _, err := reference.ParseNormalizedNamed(tag); err != nil {
+			return nil, fmt.Errorf("invalid image tag %s: %w", tag, err)
+		}
 	}
-}
 
-func (d *Docker) Build(dockerfile, srcPath, imageTag string, options ...DockerBuildOption) error {
-	opts := defaultBuildOptions()
+	imageTag := tags[0]
 
-	for _, o := range options {
-		o(opts)
+	if opts.requiredDiskSpace > 0 {
+		ok, available, err := d.CheckDiskSpace(opts.requiredDiskSpace)
+		if err == nil && !ok {
+			return nil, fmt.Errorf("insufficient disk space for build of %s: %d bytes available, %d bytes required", imageTag, available, opts.requiredDiskSpace)
+		}
 	}
 
 	// If docker is available, create a buildx builder
@@ -161,29 +896,38 @@ func (d *Docker) Build(dockerfile, srcPath, imageTag string, options ...DockerBu
 
 		builder, err = d.createBuildxBuilder()
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		// fail early rather than midway through the build, best effort as the check itself may not be possible
+		if supported, err := d.SupportsPlatform(buildPlatform); err == nil && !supported {
+			return nil, unsupportedPlatformError(buildPlatform)
 		}
 	}
 
-	// write a temporary dockerignore file
-	ignoreFile, err := os.Create(fmt.Sprintf("%s.dockerignore", dockerfile))
+	attestationArgs, err := opts.attestationArgs()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = ignoreFile.Write([]byte(strings.Join(opts.excludes, "\n")))
-	if err != nil {
-		return err
+	if len(attestationArgs) > 0 {
+		if builder == nil || tui.DockerBuildxAvailable() != nil {
+			return nil, ErrAttestationsUnsupported
+		}
+
+		// loading an image into a graph driver store drops its attestations, best effort as the check itself may not be possible
+		if info, err := d.Client.Info(context.Background()); err == nil && !usesContainerdImageStore(info) {
+			return nil, fmt.Errorf("%w: the daemon doesn't use the containerd image store, which is required to keep attestations on the loaded image", ErrAttestationsUnsupported)
+		}
 	}
 
-	err = ignoreFile.Close()
-	if err != nil {
-		return err
+	if err := opts.mergeArgsFile(); err != nil {
+		return nil, err
 	}
 
-	defer func() {
-		os.Remove(ignoreFile.Name())
-	}()
+	if err := validateBuildArgs(opts.args, opts.allowedArgs); err != nil {
+		return nil, err
+	}
 
 	buildArgsCmd := make([]string, 0)
 	for k, v := range opts.args {
@@ -191,8 +935,21 @@ func (d *Docker) Build(dockerfile, srcPath, imageTag string, options ...DockerBu
 	}
 
 	args := []string{
-		"buildx", "build", srcPath, "-f", dockerfile, "-t", imageTag, "--load", "--platform", "linux/amd64",
+		"buildx", "build", buildContext, "--load", "--platform", buildPlatform,
+	}
+
+	if dockerfile != "" {
+		args = append(args, "-f", dockerfile)
+	}
+
+	for _, tag := range tags {
+		args = append(args, "-t", tag)
+	}
+
+	if opts.pullParent {
+		args = append(args, "--pull")
 	}
+
 	// Podman doesn't support builder containers
 	if builder != nil && opts.useBuilder {
 		args = append(args, fmt.Sprintf("--builder=%s", builder.Name))
@@ -200,6 +957,16 @@ func (d *Docker) Build(dockerfile, srcPath, imageTag string, options ...DockerBu
 
 	args = append(args, buildArgsCmd...)
 
+	for k, v := range opts.labels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	for k, v := range opts.annotations {
+		args = append(args, "--annotation", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	args = append(args, attestationArgs...)
+
 	cacheTo := ""
 	cacheFrom := ""
 
@@ -225,6 +992,26 @@ func (d *Docker) Build(dockerfile, srcPath, imageTag string, options ...DockerBu
 		cacheFrom = fmt.Sprintf("--cache-from=type=local,src=%s", imageCache)
 	}
 
+	// share cache between machines (e.g. ephemeral CI runners) through a registry repository
+	dockerBuildCacheRegistry := os.Getenv("DOCKER_BUILD_CACHE_REGISTRY")
+	if dockerBuildCacheRegistry != "" {
+		imageCache, err := registryCacheRef(dockerBuildCacheRegistry, imageTag)
+		if err != nil {
+			return nil, err
+		}
+
+		cacheTo = fmt.Sprintf("--cache-to=type=registry,ref=%s,mode=max", imageCache)
+		cacheFrom = fmt.Sprintf("--cache-from=type=registry,ref=%s", imageCache)
+	}
+
+	if opts.cacheTo != "" {
+		cacheTo = fmt.Sprintf("--cache-to=%s", opts.cacheTo)
+	}
+
+	if opts.cacheFrom != "" {
+		cacheFrom = fmt.Sprintf("--cache-from=%s", opts.cacheFrom)
+	}
+
 	if cacheTo != "" {
 		args = append(args, cacheTo)
 	}
@@ -233,6 +1020,18 @@ func (d *Docker) Build(dockerfile, srcPath, imageTag string, options ...DockerBu
 		args = append(args, cacheFrom)
 	}
 
+	args = append(args, opts.extraArgs...)
+
+	iidFile, err := os.CreateTemp("", "nitric-iid-*")
+	if err != nil {
+		return nil, err
+	}
+
+	iidFile.Close()
+	defer os.Remove(iidFile.Name())
+
+	args = append(args, "--iidfile", iidFile.Name())
+
 	// The args should be compatible with either docker or podman
 	baseCommand := "docker"
 
@@ -240,16 +1039,163 @@ func (d *Docker) Build(dockerfile, srcPath, imageTag string, options ...DockerBu
 		if err := tui.PodmanAvailable(); err == nil {
 			baseCommand = "podman"
 		} else {
-			return errors.New("Docker or Podman is required, see https://docs.docker.com/engine/install/ for docker installation instructions")
+			return nil, errors.New("Docker or Podman is required, see https://docs.docker.com/engine/install/ for docker installation instructions")
+		}
+	}
+
+	logger := opts.logger
+
+	// quiet builds replace only the logger, tee writers (e.g. a build log file) still receive the full output
+	var quietOutput *tailWriter
+	if opts.quiet {
+		quietOutput = newTailWriter(quietOutputLines)
+		logger = quietOutput
+	}
+
+	logOutput := io.MultiWriter(append([]io.Writer{logger}, opts.tee...)...)
+
+	if opts.timestamps {
+		timestamped := newTimestampWriter(logOutput, time.Now())
+		defer timestamped.flush()
+
+		logOutput = timestamped
+	}
+
+	warnings := newWarningWriter()
+	cacheStats := newCacheStatsWriter()
+	resources := newResourceWriter()
+	outputs := []io.Writer{logOutput, warnings, cacheStats, resources}
+
+	var progress *progressWriter
+	if opts.onProgress != nil {
+		progress = newProgressWriter(opts.onProgress)
+		outputs = append(outputs, progress)
+	}
+
+	output := io.MultiWriter(outputs...)
+
+	// podman builds with buildah, which doesn't support BuildKit frontends
+	if baseCommand == "podman" && buildContext != "-" && !isRemoteContext(buildContext) {
+		if syntax := dockerfileSyntax(dockerfile); syntax != "" {
+			fmt.Fprintf(output, "WARNING: the dockerfile syntax directive %q is not supported by podman and will be ignored\n", syntax)
+		}
+	}
+
+	cmd := exec.CommandContext(opts.ctx, baseCommand, args...)
+	// interrupt rather than kill the build client, so it can cancel the build with the daemon before exiting
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+
+		return nil
+	}
+	cmd.WaitDelay = 10 * time.Second
+	cmd.Stdin = opts.stdin
+
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Run(); err != nil {
+		if opts.ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("build of %s timed out: %w", imageTag, opts.ctx.Err())
+		}
+
+		if opts.ctx.Err() != nil {
+			return nil, fmt.Errorf("build of %s cancelled: %w", imageTag, opts.ctx.Err())
+		}
+
+		if quietOutput != nil {
+			err = fmt.Errorf("build of %s failed: %w\n%s", imageTag, err, strings.Join(quietOutput.lines(), "\n"))
+		}
+
+		return nil, d.withResourceHint(err, resources.exhausted())
+	}
+
+	imageID, err := os.ReadFile(iidFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the ID of image %s: %w", imageTag, err)
+	}
+
+	if progress != nil {
+		progress.complete()
+	}
+
+	cacheHits, cacheMisses := cacheStats.stats()
+
+	result := &BuildResult{
+		Tags:        tags,
+		ImageID:     strings.TrimSpace(string(imageID)),
+		Warnings:    warnings.collected(),
+		CacheHits:   cacheHits,
+		CacheMisses: cacheMisses,
+	}
+
+	if host := d.Client.DaemonHost(); isRemoteDaemon(host) {
+		if err := d.verifyRemoteImage(host, result); err != nil {
+			return nil, err
+		}
+
+		result.DaemonHost = host
+	}
+
+	return result, nil
+}
+
+// verifyRemoteImage ensures every tag of a build was loaded onto the remote daemon, so the image can be deployed from the remote host.
+// The IDs aren't compared, the ID written by the builder (e.g. a manifest digest with the containerd image store) doesn't always
+// match the ID the daemon reports for the loaded image.
+func (d *Docker) verifyRemoteImage(host string, result *BuildResult) error {
+	for _, tag := range result.Tags {
+		if _, _, err := d.Client.ImageInspectWithRaw(context.Background(), tag); err != nil {
+			return fmt.Errorf("image %s was built but couldn't be found on the remote daemon %s: %w", tag, host, err)
 		}
 	}
 
-	cmd := exec.Command(baseCommand, args...)
+	return nil
+}
+
+// BuildFromContents builds an image from in-memory dockerfile contents, using srcPath as the build context.
+// The contents are piped to the build (`-f -`) rather than written to disk.
+//
+// A dockerfile read from stdin can't have its own dockerignore file and has no path to hash, so with excludes (see WithExcludes)
+// or a content tag (see WithContentTag) the contents are written to a temporary directory and built with Build instead.
+// For the same reason Build, which relies on the dockerignore file alongside the dockerfile, isn't implemented on top of this.
+func (d *Docker) BuildFromContents(dockerfileContents []byte, srcPath, imageTag string, options ...DockerBuildOption) error {
+	opts := defaultBuildOptions()
+
+	for _, o := range options {
+		o(opts)
+	}
+
+	if len(opts.excludes) > 0 || opts.contentTag {
+		return d.buildFromTempDockerfile(dockerfileContents, srcPath, imageTag, options...)
+	}
+
+	opts.stdin = bytes.NewReader(dockerfileContents)
+
+	_, err := d.build(srcPath, "-", []string{imageTag}, opts)
+
+	return err
+}
+
+// buildFromTempDockerfile builds an image from dockerfile contents written to a temporary file owned by this call,
+// which is removed once the build completes
+func (d *Docker) buildFromTempDockerfile(dockerfileContents []byte, srcPath, imageTag string, options ...DockerBuildOption) error {
+	tmpDir, err := os.MkdirTemp("", "nitric-build-*")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary build directory: %w", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
 
-	cmd.Stdout = opts.logger
-	cmd.Stderr = opts.logger
+	dockerfile := filepath.Join(tmpDir, "Dockerfile")
+
+	if err := os.WriteFile(dockerfile, dockerfileContents, 0o600); err != nil {
+		return fmt.Errorf("unable to write temporary dockerfile: %w", err)
+	}
 
-	return cmd.Run()
+	return d.Build(dockerfile, srcPath, imageTag, options...)
 }
 
 type ErrorLine struct {
@@ -261,40 +1207,143 @@ type ErrorDetail struct {
 	Message string `json:"message"`
 }
 
+// Line is a single JSON message from a docker API progress stream.
+// Both the legacy builder/pull schema (stream, status, error) and the BuildKit solve status schema (vertexes, statuses, logs) are supported.
 type Line struct {
-	Stream string `json:"stream"`
-	Status string `json:"status"`
+	Stream         string          `json:"stream"`
+	Status         string          `json:"status"`
+	ID             string          `json:"id"`
+	ProgressDetail *ProgressDetail `json:"progressDetail"`
+	Error          string          `json:"error"`
+	ErrorDetail    ErrorDetail     `json:"errorDetail"`
+
+	Vertexes []BuildkitVertex `json:"vertexes"`
+	Statuses []BuildkitStatus `json:"statuses"`
+	Logs     []BuildkitLog    `json:"logs"`
+}
+
+// ProgressDetail is the progress of a layer transfer reported by a pull, empty once the transfer completes
+type ProgressDetail struct {
+	Current int64 `json:"current"`
+	Total   int64 `json:"total"`
+}
+
+// BuildkitVertex is a single build step reported by BuildKit
+type BuildkitVertex struct {
+	Digest    string     `json:"digest"`
+	Name      string     `json:"name"`
+	Started   *time.Time `json:"started"`
+	Completed *time.Time `json:"completed"`
+	Cached    bool       `json:"cached"`
+	Error     string     `json:"error"`
+}
+
+// BuildkitStatus is the progress of an operation (e.g. a layer transfer) within a BuildKit vertex
+type BuildkitStatus struct {
+	ID      string `json:"id"`
+	Vertex  string `json:"vertex"`
+	Name    string `json:"name"`
+	Current int64  `json:"current"`
+	Total   int64  `json:"total"`
+}
+
+// BuildkitLog is output written by a BuildKit vertex, Data is base64 encoded in the JSON form
+type BuildkitLog struct {
+	Vertex string `json:"vertex"`
+	Stream int    `json:"stream"`
+	Data   []byte `json:"data"`
+}
+
+// texts returns the human readable output lines contained in the message
+func (l *Line) texts() []string {
+	texts := []string{}
+
+	if text := strings.TrimRightFunc(l.Stream, unicode.IsSpace); len(text) > 0 {
+		texts = append(texts, text)
+	}
+
+	for _, entry := range l.Logs {
+		if text := strings.TrimRightFunc(string(entry.Data), unicode.IsSpace); len(text) > 0 {
+			texts = append(texts, text)
+		}
+	}
+
+	return texts
 }
 
-func print(rd io.Reader) error {
-	var lastLine string
+// statusText returns the status reported by a pull (e.g. "43c4264eed91: Pull complete"), if any.
+// Transfer progress updates are skipped, as they're repeated many times for each layer.
+func (l *Line) statusText() (string, bool) {
+	if l.Status == "" || (l.ProgressDetail != nil && (l.ProgressDetail.Current > 0 || l.ProgressDetail.Total > 0)) {
+		return "", false
+	}
+
+	if l.ID != "" {
+		return fmt.Sprintf("%s: %s", l.ID, l.Status), true
+	}
+
+	return l.Status, true
+}
+
+// err returns the failure reported by the message, if any
+func (l *Line) err() error {
+	if l.Error != "" {
+		return errors.New(l.Error)
+	}
+
+	if l.ErrorDetail.Message != "" {
+		return errors.New(l.ErrorDetail.Message)
+	}
+
+	for _, vertex := range l.Vertexes {
+		if vertex.Error != "" {
+			return fmt.Errorf("%s: %s", vertex.Name, vertex.Error)
+		}
+	}
+
+	return nil
+}
+
+// print logs the stream output of a docker API progress response, also copying it and any pull statuses to the provided writers
+func print(rd io.Reader, tee ...io.Writer) error {
+	var streamErr error
+
+	teeWriter := io.MultiWriter(tee...)
 
 	scanner := bufio.NewScanner(rd)
+	// BuildKit log messages can exceed the default token size
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+
 	for scanner.Scan() {
-		lastLine = scanner.Text()
 		line := &Line{}
 
-		err := json.Unmarshal([]byte(lastLine), line)
+		err := json.Unmarshal(scanner.Bytes(), line)
 		if err != nil {
 			return err
 		}
 
-		text := strings.TrimRightFunc(line.Stream, unicode.IsSpace)
-
-		if len(text) > 0 {
+		for _, text := range line.texts() {
 			log.Default().Println(text)
+
+			if _, err := fmt.Fprintln(teeWriter, text); err != nil {
+				return err
+			}
 		}
-	}
 
-	errLine := &ErrorLine{}
+		// pull statuses are only copied, so pulls don't flood the log
+		if status, ok := line.statusText(); ok {
+			if _, err := fmt.Fprintln(teeWriter, status); err != nil {
+				return err
+			}
+		}
 
-	err := json.Unmarshal([]byte(lastLine), errLine)
-	if err != nil {
-		return err
+		if err := line.err(); err != nil && streamErr == nil {
+			streamErr = err
+		}
 	}
 
-	if errLine.Error != "" {
-		return errors.New(errLine.Error)
+	if streamErr != nil {
+		return streamErr
 	}
 
 	return scanner.Err()
@@ -326,49 +1375,138 @@ func print(rd io.Reader) error {
 // 	return imgs, err
 // }
 
-func (d *Docker) ImagePull(rawImage string, opts types.ImagePullOptions) error {
-	resp, err := d.Client.ImagePull(context.Background(), rawImage, opts)
+// ImagePull pulls an image, logging progress and copying it to any provided writers
+// Credentials stored by `docker login` are used when opts doesn't provide registry auth.
+func (d *Docker) ImagePull(rawImage string, opts types.ImagePullOptions, logs ...io.Writer) error {
+	if opts.RegistryAuth == "" {
+		// fall back to an anonymous pull if the stored credentials can't be resolved
+		if encodedAuth, err := registryAuthForImage(rawImage); err == nil {
+			opts.RegistryAuth = encodedAuth
+		}
+	}
+
+	ctx := context.Background()
+
+	if d.pullTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, d.pullTimeout)
+		defer cancel()
+	}
+
+	resp, err := d.Client.ImagePull(ctx, rawImage, opts)
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("pull of %s timed out after %s: %w", rawImage, d.pullTimeout, ctx.Err())
+	}
+
 	if err != nil {
+		if isCertificateError(err) {
+			return certificateError(rawImage, err)
+		}
+
 		return errors.WithMessage(err, "Pull")
 	}
 
 	defer resp.Close()
 
-	return print(resp)
+	// certificate failures are usually reported in the progress stream rather than the initial response
+	if err := print(resp, logs...); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("pull of %s timed out after %s: %w", rawImage, d.pullTimeout, ctx.Err())
+		}
+
+		if isCertificateError(err) {
+			return certificateError(rawImage, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// containerName returns the name a container is created with, including the client's name prefix
+func (d *Docker) containerName(name string) string {
+	if d.namePrefix == "" || name == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%s-%s", d.namePrefix, name)
 }
 
+// ContainerCreate creates a container named with the client's name prefix and labeled with its namespace and session,
+// the caller's config is left unmodified
 func (d *Docker) ContainerCreate(config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, name string) (string, error) {
+	name = d.containerName(name)
+
+	labeledConfig := *config
+	labeledConfig.Labels = d.clientLabels(config.Labels)
+	config = &labeledConfig
+
 	resp, err := d.Client.ContainerCreate(context.Background(), config, hostConfig, networkingConfig, nil, name)
 	if err != nil {
-		return "", errors.WithMessage(err, "ContainerCreate")
+		err = errors.WithMessage(err, "ContainerCreate")
+
+		// only privileged ports are restricted by rootless engines, explain the failure if they may be the cause
+		if len(privilegedHostPorts(hostConfig)) > 0 {
+			if info, infoErr := d.Info(); infoErr == nil {
+				if rootlessErr := checkRootlessPorts(info, hostConfig); rootlessErr != nil {
+					return "", goerrors.Join(err, rootlessErr)
+				}
+			}
+		}
+
+		return "", err
 	}
 
 	return resp.ID, nil
 }
 
-func (d *Docker) RemoveByLabel(labels map[string]string) error {
-	opts := container.ListOptions{
-		All:     true,
-		Filters: filters.NewArgs(),
+type removeOptions struct {
+	// directory to write each container's logs to before it's removed
+	logDir string
+}
+
+type RemoveOption func(*removeOptions)
+
+// WithLogDir - writes the logs of each container to <dir>/<container name>.log before removing it, for post-mortems
+func WithLogDir(dir string) RemoveOption {
+	return func(o *removeOptions) {
+		o.logDir = dir
 	}
+}
 
-	for name, value := range labels {
-		opts.Filters.Add("label", fmt.Sprintf("%s=%s", name, value))
+// RemoveByLabel force removes the containers matching the labels. Every container is removed even if writing its logs (see WithLogDir)
+// or removing another container fails, the errors are joined.
+func (d *Docker) RemoveByLabel(labels map[string]string, options ...RemoveOption) error {
+	opts := &removeOptions{}
+
+	for _, o := range options {
+		o(opts)
 	}
 
-	res, err := d.Client.ContainerList(context.Background(), opts)
+	res, err := d.listByLabel(labels)
 	if err != nil {
 		return err
 	}
 
+	removeErrors := []error{}
+
 	for _, con := range res {
+		// a failed log dump shouldn't leave the container behind, report it once every container has been removed
+		if opts.logDir != "" {
+			if err := d.dumpLogsToDir(con, opts.logDir); err != nil {
+				removeErrors = append(removeErrors, err)
+			}
+		}
+
 		err = d.Client.ContainerRemove(context.Background(), con.ID, container.RemoveOptions{Force: true})
 		if err != nil {
-			return err
+			removeErrors = append(removeErrors, err)
 		}
 	}
 
-	return nil
+	return goerrors.Join(removeErrors...)
 }
 
 // func (d *Docker) Logger(stackPath string) ContainerLogger {
@@ -0,0 +1,304 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+// captured from `docker build` against the legacy builder
+const legacyBuildOutput = `{"stream":"Step 1/2 : FROM alpine"}
+{"stream":"\n"}
+{"stream":" ---> 05455a08881e\n"}
+{"stream":"Step 2/2 : RUN exit 1"}
+{"stream":"\n"}
+{"stream":" ---> Running in 4ae0e8e8e0a4\n"}
+{"errorDetail":{"code":1,"message":"The command '/bin/sh -c exit 1' returned a non-zero code: 1"},"error":"The command '/bin/sh -c exit 1' returned a non-zero code: 1"}
+`
+
+// captured from `docker buildx build --progress=rawjson` against BuildKit
+const buildkitBuildOutput = `{"vertexes":[{"digest":"sha256:4f5b6c2e1f7a","name":"[1/2] FROM docker.io/library/alpine","started":"2024-10-01T01:02:03.000000000Z"}]}
+{"vertexes":[{"digest":"sha256:4f5b6c2e1f7a","name":"[1/2] FROM docker.io/library/alpine","started":"2024-10-01T01:02:03.000000000Z","completed":"2024-10-01T01:02:04.000000000Z","cached":true}]}
+{"vertexes":[{"digest":"sha256:9a8b7c6d5e4f","inputs":["sha256:4f5b6c2e1f7a"],"name":"[2/2] RUN echo hello && exit 1","started":"2024-10-01T01:02:04.000000000Z"}]}
+{"logs":[{"vertex":"sha256:9a8b7c6d5e4f","stream":1,"data":"aGVsbG8K","timestamp":"2024-10-01T01:02:04.100000000Z"}]}
+{"vertexes":[{"digest":"sha256:9a8b7c6d5e4f","inputs":["sha256:4f5b6c2e1f7a"],"name":"[2/2] RUN echo hello && exit 1","started":"2024-10-01T01:02:04.000000000Z","completed":"2024-10-01T01:02:05.000000000Z","error":"process \"/bin/sh -c echo hello && exit 1\" did not complete successfully: exit code: 1"}]}
+`
+
+// captured from an image pull
+const pullOutput = `{"status":"Pulling from library/alpine","id":"latest"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"43c4264eed91"}
+{"status":"Downloading","progressDetail":{"current":1048576,"total":3623807},"progress":"[==============>                                    ]  1.049MB/3.624MB","id":"43c4264eed91"}
+{"status":"Pull complete","progressDetail":{},"id":"43c4264eed91"}
+{"status":"Digest: sha256:beefdbd8a1da6d2915566fde36db9db0b524eb737fc57cd1367effd16dc0d06d"}
+{"status":"Status: Downloaded newer image for alpine:latest"}
+`
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantOutput []string
+		wantErr    string
+	}{
+		{
+			name:       "legacy builder",
+			input:      legacyBuildOutput,
+			wantOutput: []string{"Step 1/2 : FROM alpine", " ---> 05455a08881e", "Step 2/2 : RUN exit 1", " ---> Running in 4ae0e8e8e0a4"},
+			wantErr:    "The command '/bin/sh -c exit 1' returned a non-zero code: 1",
+		},
+		{
+			name:       "buildkit",
+			input:      buildkitBuildOutput,
+			wantOutput: []string{"hello"},
+			wantErr:    "[2/2] RUN echo hello && exit 1: process \"/bin/sh -c echo hello && exit 1\" did not complete successfully: exit code: 1",
+		},
+		{
+			name:  "pull",
+			input: pullOutput,
+			wantOutput: []string{
+				"latest: Pulling from library/alpine",
+				"43c4264eed91: Pulling fs layer",
+				"43c4264eed91: Pull complete",
+				"Digest: sha256:beefdbd8a1da6d2915566fde36db9db0b524eb737fc57cd1367effd16dc0d06d",
+				"Status: Downloaded newer image for alpine:latest",
+			},
+		},
+		{
+			name:       "empty",
+			input:      "",
+			wantOutput: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &bytes.Buffer{}
+
+			err := print(strings.NewReader(tt.input), output)
+
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+
+			if gotErr != tt.wantErr {
+				t.Errorf("print() error = %q, want %q", gotErr, tt.wantErr)
+			}
+
+			gotOutput := strings.FieldsFunc(output.String(), func(r rune) bool { return r == '\n' })
+			if diff := cmp.Diff(tt.wantOutput, gotOutput); diff != "" {
+				t.Errorf("print() output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestVerifyRemoteImage(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/images/api:latest/json") {
+			_ = json.NewEncoder(w).Encode(types.ImageInspect{ID: "sha256:built"})
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "No such image"})
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	if err := d.verifyRemoteImage("tcp://builder:2376", &BuildResult{Tags: []string{"api:latest"}, ImageID: "sha256:built"}); err != nil {
+		t.Errorf("expected the built image to be verified, got %v", err)
+	}
+
+	// the builder's ID may be a manifest digest rather than the ID reported by the daemon
+	if err := d.verifyRemoteImage("tcp://builder:2376", &BuildResult{Tags: []string{"api:latest"}, ImageID: "sha256:manifest"}); err != nil {
+		t.Errorf("expected the tag to be verified regardless of the built image ID, got %v", err)
+	}
+
+	err := d.verifyRemoteImage("tcp://builder:2376", &BuildResult{Tags: []string{"worker:latest"}, ImageID: "sha256:built"})
+	if err == nil || !strings.Contains(err.Error(), "tcp://builder:2376") {
+		t.Errorf("expected an error naming the remote host when the tag is missing, got %v", err)
+	}
+}
+
+func TestAttestationArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []DockerBuildOption
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "no attestations",
+			want: []string{},
+		},
+		{
+			name:    "provenance and sbom",
+			options: []DockerBuildOption{WithProvenance("max"), WithSBOM(true)},
+			want:    []string{"--provenance=mode=max", "--sbom=true"},
+		},
+		{
+			name:    "invalid provenance mode",
+			options: []DockerBuildOption{WithProvenance("full")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultBuildOptions()
+			for _, o := range tt.options {
+				o(opts)
+			}
+
+			got, err := opts.attestationArgs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("attestationArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); !tt.wantErr && diff != "" {
+				t.Errorf("unexpected args (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRegistryCacheRef(t *testing.T) {
+	tests := []struct {
+		name       string
+		repository string
+		imageTag   string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "local image",
+			repository: "ghcr.io/acme/build-cache",
+			imageTag:   "my-stack_services-api",
+			want:       "ghcr.io/acme/build-cache:my-stack-services-api-latest",
+		},
+		{
+			name:       "registry image",
+			repository: "ghcr.io/acme/build-cache",
+			imageTag:   "registry.example.com:5000/acme/api:v1",
+			want:       "ghcr.io/acme/build-cache:acme-api-v1",
+		},
+		{
+			name:       "repository with a tag",
+			repository: "ghcr.io/acme/build-cache:latest",
+			imageTag:   "api",
+			want:       "ghcr.io/acme/build-cache:api-latest",
+		},
+		{
+			name:       "uppercase repository",
+			repository: "ghcr.io/Acme/cache",
+			imageTag:   "api",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registryCacheRef(tt.repository, tt.imageTag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("registryCacheRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("registryCacheRef() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseTimeoutReachesTransport(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			// an unresponsive daemon
+			time.Sleep(500 * time.Millisecond)
+			_ = json.NewEncoder(w).Encode([]types.Container{})
+		case strings.HasSuffix(r.URL.Path, "/events"):
+			// a stream that responds at once, then stays idle for longer than the timeout
+			w.(http.Flusher).Flush()
+			time.Sleep(300 * time.Millisecond)
+			_ = json.NewEncoder(w).Encode(events.Message{Type: events.ContainerEventType, Action: events.ActionStart})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer daemon.Close()
+
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	dockerClient, err := newClient(&clientOptions{apiVersion: "1.44", responseTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dockerClient.Close()
+
+	if _, err := dockerClient.ContainerList(context.Background(), container.ListOptions{}); err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Errorf("expected the call to time out awaiting the daemon, got %v", err)
+	}
+
+	messages, errs := dockerClient.Events(context.Background(), types.EventsOptions{})
+
+	select {
+	case msg := <-messages:
+		if msg.Action != events.ActionStart {
+			t.Errorf("expected start event, got %s", msg.Action)
+		}
+	case err := <-errs:
+		t.Errorf("expected the stream to outlast the response timeout, got %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestConfigureTransportKeepAlive(t *testing.T) {
+	remote := &http.Transport{}
+	configureTransport(remote, "tcp://builder:2376", &clientOptions{keepAlive: 15 * time.Second, responseTimeout: time.Second})
+
+	if remote.DialContext == nil {
+		t.Error("expected a keep-alive dialer for a remote daemon")
+	}
+
+	if remote.ResponseHeaderTimeout != time.Second {
+		t.Errorf("expected a response timeout of 1s, got %s", remote.ResponseHeaderTimeout)
+	}
+
+	local := &http.Transport{}
+	configureTransport(local, "unix:///var/run/docker.sock", &clientOptions{keepAlive: 15 * time.Second})
+
+	if local.DialContext != nil {
+		t.Error("expected the dialer of a local socket to be left as is")
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// ContentHashLabel is the image label used to record the content hash of the inputs an image was built from
+const ContentHashLabel = "io.nitric.content-hash"
+
+// excludePattern is a compiled .dockerignore pattern, exclusion patterns (prefixed with !) re-include the paths they match
+type excludePattern struct {
+	regexp    *regexp.Regexp
+	exclusion bool
+}
+
+// excludeMatcher applies .dockerignore patterns the same way as docker does when sending a build context:
+// patterns are evaluated in order, the last matching pattern wins, ! re-includes paths and ** matches any number of directories
+type excludeMatcher struct {
+	patterns      []excludePattern
+	hasExclusions bool
+}
+
+// newExcludeMatcher compiles the .dockerignore patterns, skipping blank lines and comments
+func newExcludeMatcher(excludes []string) (*excludeMatcher, error) {
+	m := &excludeMatcher{}
+
+	for _, pattern := range excludes {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		exclusion := false
+		if pattern[0] == '!' {
+			exclusion = true
+			pattern = strings.TrimSpace(pattern[1:])
+
+			if pattern == "" {
+				return nil, errors.New("invalid exclude pattern !: a ! must be followed by a pattern")
+			}
+		}
+
+		pattern = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(pattern)), "/")
+
+		re, err := compileExcludePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+
+		m.patterns = append(m.patterns, excludePattern{regexp: re, exclusion: exclusion})
+		m.hasExclusions = m.hasExclusions || exclusion
+	}
+
+	return m, nil
+}
+
+// compileExcludePattern translates a slash separated .dockerignore pattern to a regular expression,
+// * and ? don't match a separator while ** matches any number of directories
+func compileExcludePattern(pattern string) (*regexp.Regexp, error) {
+	expr := strings.Builder{}
+	expr.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+
+				// **/ is treated as **
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+				}
+
+				if i+1 == len(pattern) {
+					expr.WriteString(".*")
+				} else {
+					expr.WriteString("(.*/)?")
+				}
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+
+			class := pattern[i+1 : i+end]
+			// filepath.Match style negated classes
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			expr.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// excluded reports whether the slash separated relative path is left out of the build context, a path is excluded when it or
+// any of its parent directories matches the last applicable pattern
+func (m *excludeMatcher) excluded(relPath string) bool {
+	matched := false
+	parents := strings.Split(relPath, "/")
+	parents = parents[:len(parents)-1]
+
+	for _, pattern := range m.patterns {
+		// only exclusions can change an excluded path, only inclusions an included one
+		if pattern.exclusion != matched {
+			continue
+		}
+
+		match := pattern.regexp.MatchString(relPath)
+
+		for i := 0; !match && i < len(parents); i++ {
+			match = pattern.regexp.MatchString(strings.Join(parents[:i+1], "/"))
+		}
+
+		if match {
+			matched = !pattern.exclusion
+		}
+	}
+
+	return matched
+}
+
+// contentHash computes a deterministic hash of a build's inputs, being the dockerfile contents,
+// the build args and the path, mode and contents of every non-excluded file in the build context.
+func contentHash(dockerfile, srcPath string, opts *dockerBuildOptions) (string, error) {
+	if err := opts.mergeArgsFile(); err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+
+	dockerfileContents, err := os.ReadFile(dockerfile)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(h, "dockerfile:%x\n", sha256.Sum256(dockerfileContents))
+
+	argKeys := make([]string, 0, len(opts.args))
+	for k := range opts.args {
+		argKeys = append(argKeys, k)
+	}
+
+	sort.Strings(argKeys)
+
+	for _, k := range argKeys {
+		fmt.Fprintf(h, "arg:%s=%s\n", k, opts.args[k])
+	}
+
+	excludes, err := newExcludeMatcher(opts.excludes)
+	if err != nil {
+		return "", err
+	}
+
+	// filepath.WalkDir visits entries in lexical order, keeping the hash stable
+	err = filepath.WalkDir(srcPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return err
+		}
+
+		relPath = filepath.ToSlash(relPath)
+
+		if relPath != "." && excludes.excluded(relPath) {
+			// a ! pattern may re-include files within an excluded directory
+			if entry.IsDir() && !excludes.hasExclusions {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fh := sha256.New()
+		if _, err := io.Copy(fh, f); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(h, "file:%s:%o:%x\n", relPath, info.Mode().Perm(), fh.Sum(nil))
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to hash build context %s: %w", srcPath, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// EnsureImage builds the image only when no existing image with the tag was built from identical inputs.
+// The content hash of the inputs is stored on the image using the ContentHashLabel label.
+// Returns the result of the build and true if a build was performed, or the existing image's tag and ID and false if it was reused.
+func (d *Docker) EnsureImage(dockerfile, srcPath, imageTag string, options ...DockerBuildOption) (*BuildResult, bool, error) {
+	opts := defaultBuildOptions()
+
+	for _, o := range options {
+		o(opts)
+	}
+
+	hash, err := contentHash(dockerfile, srcPath, opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, _, err := d.Client.ImageInspectWithRaw(context.Background(), imageTag)
+	if err != nil && !client.IsErrNotFound(err) {
+		return nil, false, fmt.Errorf("unable to inspect image %s: %w", imageTag, err)
+	}
+
+	if err == nil && existing.Config != nil && existing.Config.Labels[ContentHashLabel] == hash {
+		return &BuildResult{Tags: []string{imageTag}, ImageID: existing.ID}, false, nil
+	}
+
+	options = append(options, WithLabels(map[string]string{ContentHashLabel: hash}))
+
+	result, err := d.BuildWithTags(dockerfile, srcPath, []string{imageTag}, options...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, true, nil
+}
@@ -0,0 +1,156 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExcludeMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		excludes []string
+		path     string
+		want     bool
+	}{
+		{name: "matching file", excludes: []string{"*.txt"}, path: "notes.txt", want: true},
+		{name: "star doesn't cross directories", excludes: []string{"*.txt"}, path: "docs/notes.txt", want: false},
+		{name: "excluded parent directory", excludes: []string{"node_modules"}, path: "node_modules/left-pad/index.js", want: true},
+		{name: "re-included file", excludes: []string{"*.txt", "!keep.txt"}, path: "keep.txt", want: false},
+		{name: "re-include doesn't affect other files", excludes: []string{"*.txt", "!keep.txt"}, path: "drop.txt", want: true},
+		{name: "later pattern wins", excludes: []string{"!keep.txt", "*.txt"}, path: "keep.txt", want: true},
+		{name: "re-included file in excluded directory", excludes: []string{"build", "!build/manifest.json"}, path: "build/manifest.json", want: false},
+		{name: "double star matches any depth", excludes: []string{"**/*.log"}, path: "a/b/c/debug.log", want: true},
+		{name: "double star matches no directories", excludes: []string{"**/*.log"}, path: "debug.log", want: true},
+		{name: "trailing double star", excludes: []string{"tmp/**"}, path: "tmp/a/b", want: true},
+		{name: "double star re-include", excludes: []string{"**/*.log", "!**/keep.log"}, path: "a/keep.log", want: false},
+		{name: "leading slash", excludes: []string{"/secrets"}, path: "secrets/key.pem", want: true},
+		{name: "comments and blank lines", excludes: []string{"# *.go", "", "*.md"}, path: "main.go", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newExcludeMatcher(tt.excludes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := m.excluded(tt.path); got != tt.want {
+				t.Errorf("excluded(%q) with %v = %v, want %v", tt.path, tt.excludes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentHashIncludesReincludedFiles(t *testing.T) {
+	srcPath := t.TempDir()
+	dockerfile := filepath.Join(t.TempDir(), "Dockerfile")
+
+	writeFile := func(path, contents string) {
+		t.Helper()
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(dockerfile, "FROM alpine\nCOPY . .\n")
+	writeFile(filepath.Join(srcPath, "keep.txt"), "v1")
+	writeFile(filepath.Join(srcPath, "drop.txt"), "v1")
+	writeFile(filepath.Join(srcPath, "build", "manifest.json"), "v1")
+
+	hash := func() string {
+		t.Helper()
+
+		opts := defaultBuildOptions()
+		WithExcludes([]string{"*.txt", "!keep.txt", "build", "!build/manifest.json"})(opts)
+
+		h, err := contentHash(dockerfile, srcPath, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return h
+	}
+
+	original := hash()
+
+	writeFile(filepath.Join(srcPath, "drop.txt"), "v2")
+
+	if hash() != original {
+		t.Error("expected a change to an excluded file not to change the hash")
+	}
+
+	writeFile(filepath.Join(srcPath, "keep.txt"), "v2")
+
+	changed := hash()
+	if changed == original {
+		t.Error("expected a change to a re-included file to change the hash")
+	}
+
+	writeFile(filepath.Join(srcPath, "build", "manifest.json"), "v2")
+
+	if hash() == changed {
+		t.Error("expected a change to a file re-included from an excluded directory to change the hash")
+	}
+}
+
+func TestContentTagChangesWithReincludedFiles(t *testing.T) {
+	srcPath := t.TempDir()
+	dockerfile := filepath.Join(t.TempDir(), "Dockerfile")
+
+	for path, contents := range map[string]string{dockerfile: "FROM alpine\nCOPY . .\n", filepath.Join(srcPath, "keep.txt"): "v1"} {
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tag := func() string {
+		t.Helper()
+
+		opts := defaultBuildOptions()
+		WithExcludes([]string{"*.txt", "!keep.txt"})(opts)
+
+		hash, err := contentHash(dockerfile, srcPath, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tag, err := contentAddressableTag("api:latest", hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tag
+	}
+
+	original := tag()
+
+	if err := os.WriteFile(filepath.Join(srcPath, "keep.txt"), []byte("v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed := tag(); changed == original {
+		t.Errorf("expected the content tag to change with a re-included file, got %s both times", changed)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// EventsByLabel streams daemon events for the images and containers carrying every provided label,
+// e.g. the StackLabel of a stack or a label applied to a build with WithLabels.
+//
+// Both channels are closed when the context is cancelled or the stream fails, the error channel receives the failure first.
+// Cancel the context when the build or stack ends to release the stream.
+func (d *Docker) EventsByLabel(ctx context.Context, labels map[string]string) (<-chan events.Message, <-chan error) {
+	args := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("type", string(events.ImageEventType)),
+	)
+
+	for name, value := range labels {
+		args.Add("label", fmt.Sprintf("%s=%s", name, value))
+	}
+
+	messages, errs, _ := d.streamEvents(ctx, args, nil)
+
+	return messages, errs
+}
+
+// WatchContainer streams the lifecycle events (e.g. start, die and health_status) of a single container,
+// returns ErrNotFound if the container doesn't exist.
+// The stream is subscribed before the container is inspected, so no event is missed between the two.
+//
+// Both channels are closed once the container is removed, when the context is cancelled or when the stream fails,
+// the error channel receives the failure first.
+func (d *Docker) WatchContainer(ctx context.Context, nameOrID string) (<-chan events.Message, <-chan error, error) {
+	// the container filter matches either the container's name or ID
+	args := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("container", nameOrID),
+	)
+
+	messages, errs, stop := d.streamEvents(ctx, args, func(msg events.Message) bool {
+		return msg.Action == events.ActionDestroy
+	})
+
+	_, err := d.Client.ContainerInspect(ctx, nameOrID)
+	if client.IsErrNotFound(err) {
+		stop()
+		return nil, nil, fmt.Errorf("container %s: %w", nameOrID, ErrNotFound)
+	}
+
+	if err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	return messages, errs, nil
+}
+
+// streamEvents relays the daemon events matching args until the context is cancelled, the stream fails
+// or last returns true for a relayed event, or stop is called
+func (d *Docker) streamEvents(ctx context.Context, args filters.Args, last func(events.Message) bool) (<-chan events.Message, <-chan error, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	messages, errs := d.Client.Events(ctx, types.EventsOptions{Filters: args})
+
+	out := make(chan events.Message)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer cancel()
+		defer close(out)
+		defer close(outErrs)
+
+		for {
+			select {
+			case msg := <-messages:
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					return
+				}
+
+				if last != nil && last(msg) {
+					return
+				}
+			case err := <-errs:
+				// a cancelled context is the expected end of the stream, not a failure
+				if ctx.Err() == nil {
+					outErrs <- err
+				}
+
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, outErrs, cancel
+}
@@ -0,0 +1,160 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEventsByLabel(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/events") {
+			http.NotFound(w, r)
+			return
+		}
+
+		if !strings.Contains(r.URL.Query().Get("filters"), "io.nitric.stack=test") {
+			t.Errorf("expected events to be filtered by stack label, got %s", r.URL.Query().Get("filters"))
+		}
+
+		_ = json.NewEncoder(w).Encode(events.Message{Type: events.ImageEventType, Action: "pull"})
+		w.(http.Flusher).Flush()
+
+		// hold the stream open until the client goes away
+		<-r.Context().Done()
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	messages, errs := d.EventsByLabel(ctx, map[string]string{StackLabel: "test"})
+
+	select {
+	case msg := <-messages:
+		if msg.Action != "pull" {
+			t.Errorf("expected pull event, got %s", msg.Action)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	cancel()
+
+	// both channels must close once the context is cancelled, without reporting an error
+	select {
+	case _, ok := <-messages:
+		if ok {
+			t.Error("expected messages channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("messages channel not closed after cancel")
+	}
+
+	if err, ok := <-errs; ok {
+		t.Errorf("expected no error after cancel, got %v", err)
+	}
+}
+
+func TestWatchContainerClosesOnDestroy(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/api/json"):
+			_ = json.NewEncoder(w).Encode(types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{ID: "abc123"}})
+		case strings.HasSuffix(r.URL.Path, "/events"):
+			if !strings.Contains(r.URL.Query().Get("filters"), `"api"`) {
+				t.Errorf("expected events to be filtered by container name, got %s", r.URL.Query().Get("filters"))
+			}
+
+			for _, action := range []events.Action{events.ActionDie, events.ActionDestroy, events.ActionCreate} {
+				_ = json.NewEncoder(w).Encode(events.Message{Type: events.ContainerEventType, Action: action, Actor: events.Actor{ID: "abc123"}})
+			}
+
+			w.(http.Flusher).Flush()
+
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	messages, errs, err := d.WatchContainer(context.Background(), "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := []events.Action{}
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				if diff := cmp.Diff([]events.Action{events.ActionDie, events.ActionDestroy}, actions); diff != "" {
+					t.Errorf("unexpected events (-want +got):\n%s", diff)
+				}
+
+				if err, ok := <-errs; ok {
+					t.Errorf("expected no error once the container was destroyed, got %v", err)
+				}
+
+				return
+			}
+
+			actions = append(actions, msg.Action)
+		case <-timeout:
+			t.Fatal("watch not closed after the container was destroyed")
+		}
+	}
+}
+
+func TestWatchContainerNotFound(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "No such container"})
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	if _, _, err := d.WatchContainer(context.Background(), "gone"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing container, got %v", err)
+	}
+}
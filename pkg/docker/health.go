@@ -0,0 +1,103 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// healthPollInterval is how often container health is checked while waiting
+const healthPollInterval = 500 * time.Millisecond
+
+// waitHealthy waits until a container reports healthy, or is running when it has no healthcheck.
+// Fails if the container stops or reports unhealthy.
+func (d *Docker) waitHealthy(ctx context.Context, nameOrID string) error {
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+
+	for {
+		info, err := d.Client.ContainerInspect(ctx, nameOrID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("container %s not healthy: %w", nameOrID, ctx.Err())
+			}
+
+			return fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+		}
+
+		if info.State != nil {
+			if !info.State.Running && info.State.Status != "created" && info.State.Status != "restarting" {
+				status := ContainerStatus{ExitCode: info.State.ExitCode, Error: info.State.Error, OOMKilled: info.State.OOMKilled}
+				return fmt.Errorf("container %s stopped before becoming healthy: %s", nameOrID, status.ExitReason())
+			}
+
+			switch {
+			case info.State.Health == nil:
+				if info.State.Running {
+					return nil
+				}
+			case info.State.Health.Status == types.Healthy:
+				return nil
+			case info.State.Health.Status == types.Unhealthy:
+				return fmt.Errorf("container %s is unhealthy: %s", nameOrID, lastHealthOutput(info.State.Health))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("container %s not healthy: %w", nameOrID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// lastHealthOutput returns the output of the most recent healthcheck, describing why a container is unhealthy
+func lastHealthOutput(health *types.Health) string {
+	if len(health.Log) == 0 {
+		return "no healthcheck output"
+	}
+
+	last := health.Log[len(health.Log)-1]
+
+	return fmt.Sprintf("healthcheck exited with status %d: %s", last.ExitCode, strings.TrimSpace(last.Output))
+}
+
+// WaitAllHealthy waits concurrently for every container to report healthy (or to be running, for containers without a healthcheck).
+// Returns nil once all are healthy, otherwise the first failure, naming the container and the reason, e.g. a failing healthcheck,
+// the container exiting or the timeout elapsing. The remaining waits are cancelled on the first failure.
+func (d *Docker) WaitAllHealthy(ctx context.Context, nameOrIDs []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	for _, nameOrID := range nameOrIDs {
+		nameOrID := nameOrID
+
+		group.Go(func() error {
+			return d.waitHealthy(groupCtx, nameOrID)
+		})
+	}
+
+	return group.Wait()
+}
@@ -0,0 +1,158 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+)
+
+// LayerDiff describes a step of two images' build histories that differs between them.
+// A side is nil when its image has fewer steps than the other.
+type LayerDiff struct {
+	// position of the step from the base of the images, 0 is the first step
+	Index int
+	A     *image.HistoryResponseItem
+	B     *image.HistoryResponseItem
+}
+
+// history returns the build history of an image, from its base to its last step
+func (d *Docker) history(imageID string) ([]image.HistoryResponseItem, error) {
+	history, err := d.Client.ImageHistory(context.Background(), imageID)
+	if client.IsErrNotFound(err) {
+		return nil, fmt.Errorf("image %s: %w", imageID, ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve history of image %s: %w", imageID, err)
+	}
+
+	// the daemon reports the most recent step first
+	slices.Reverse(history)
+
+	return history, nil
+}
+
+// stepLayers returns the diff ID of the layer produced by each step of an image's history, empty for steps that didn't produce one
+// (e.g. CMD or ENV). Only steps with a size produce a layer, nil is returned when they can't be matched with the image's layers.
+func (d *Docker) stepLayers(imageID string, history []image.HistoryResponseItem) ([]string, error) {
+	inspect, _, err := d.Client.ImageInspectWithRaw(context.Background(), imageID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to inspect image %s: %w", imageID, err)
+	}
+
+	layers := []string{}
+	if inspect.RootFS.Type == "layers" {
+		layers = inspect.RootFS.Layers
+	}
+
+	stepLayers := make([]string, len(history))
+	next := 0
+
+	for i, step := range history {
+		if step.Size == 0 {
+			continue
+		}
+
+		if next >= len(layers) {
+			return nil, nil
+		}
+
+		stepLayers[i] = layers[next]
+		next++
+	}
+
+	if next != len(layers) {
+		return nil, nil
+	}
+
+	return stepLayers, nil
+}
+
+// sameStep reports whether two history steps produced the same layer. Image IDs are compared when both are known, otherwise
+// the diff IDs of the layers produced by the steps are compared when both are known, as steps of pulled or BuildKit built images
+// only report "<missing>". Steps without layers (or whose layers are unknown) are compared by instruction and size, which can
+// miss changes, e.g. a BuildKit COPY of files of the same size.
+func sameStep(a, b image.HistoryResponseItem, layerA, layerB string) bool {
+	if a.ID != "<missing>" && b.ID != "<missing>" {
+		return a.ID == b.ID
+	}
+
+	if layerA != "" && layerB != "" {
+		return layerA == layerB
+	}
+
+	return a.CreatedBy == b.CreatedBy && a.Size == b.Size
+}
+
+// DiffImages compares the build histories of two images (e.g. an image and its rebuild) to find where they diverge,
+// which points at the instruction that invalidated the build cache. The first differing step and every step after it
+// are returned, as all of them were rebuilt. The images share every step when the returned slice is empty.
+func (d *Docker) DiffImages(idA, idB string) ([]LayerDiff, error) {
+	historyA, err := d.history(idA)
+	if err != nil {
+		return nil, err
+	}
+
+	historyB, err := d.history(idB)
+	if err != nil {
+		return nil, err
+	}
+
+	layersA, err := d.stepLayers(idA, historyA)
+	if err != nil {
+		return nil, err
+	}
+
+	layersB, err := d.stepLayers(idB, historyB)
+	if err != nil {
+		return nil, err
+	}
+
+	// layers are only compared when they're known for both images
+	if layersA == nil || layersB == nil {
+		layersA = make([]string, len(historyA))
+		layersB = make([]string, len(historyB))
+	}
+
+	diverged := 0
+	for diverged < min(len(historyA), len(historyB)) && sameStep(historyA[diverged], historyB[diverged], layersA[diverged], layersB[diverged]) {
+		diverged++
+	}
+
+	diffs := []LayerDiff{}
+
+	for i := diverged; i < max(len(historyA), len(historyB)); i++ {
+		diff := LayerDiff{Index: i}
+
+		if i < len(historyA) {
+			diff.A = &historyA[i]
+		}
+
+		if i < len(historyB) {
+			diff.B = &historyB[i]
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	return diffs, nil
+}
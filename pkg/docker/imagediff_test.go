@@ -0,0 +1,105 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffImages(t *testing.T) {
+	// histories as reported by the daemon, most recent step first
+	histories := map[string][]image.HistoryResponseItem{
+		"old": {
+			{ID: "sha256:old", CreatedBy: "CMD [\"node\" \"index.js\"]"},
+			{ID: "<missing>", CreatedBy: "COPY . . # buildkit", Size: 1200},
+			{ID: "<missing>", CreatedBy: "RUN npm ci # buildkit", Size: 50000},
+			{ID: "<missing>", CreatedBy: "COPY package.json . # buildkit", Size: 300},
+			{ID: "<missing>", CreatedBy: "/bin/sh -c #(nop) ADD file:base in /", Size: 7000},
+		},
+		"new": {
+			{ID: "sha256:new", CreatedBy: "CMD [\"node\" \"index.js\"]"},
+			{ID: "<missing>", CreatedBy: "COPY . . # buildkit", Size: 1200},
+			{ID: "<missing>", CreatedBy: "RUN npm ci # buildkit", Size: 50000},
+			{ID: "<missing>", CreatedBy: "COPY package.json . # buildkit", Size: 300},
+			{ID: "<missing>", CreatedBy: "/bin/sh -c #(nop) ADD file:base in /", Size: 7000},
+		},
+	}
+
+	// the changed COPY step has the same instruction and size in both images, only its layer differs
+	layers := map[string][]string{
+		"old": {"sha256:base", "sha256:package", "sha256:modules", "sha256:src-old"},
+		"new": {"sha256:base", "sha256:package", "sha256:modules", "sha256:src-new"},
+	}
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for id, layers := range layers {
+			if strings.HasSuffix(r.URL.Path, "/images/"+id+"/json") {
+				_ = json.NewEncoder(w).Encode(types.ImageInspect{ID: "sha256:" + id, RootFS: types.RootFS{Type: "layers", Layers: layers}})
+				return
+			}
+		}
+
+		for id, history := range histories {
+			if strings.HasSuffix(r.URL.Path, "/images/"+id+"/history") {
+				_ = json.NewEncoder(w).Encode(history)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "No such image"})
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	diffs, err := d.DiffImages("old", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []LayerDiff{
+		{Index: 3, A: &histories["old"][1], B: &histories["new"][1]},
+		{Index: 4, A: &histories["old"][0], B: &histories["new"][0]},
+	}
+
+	if diff := cmp.Diff(want, diffs); diff != "" {
+		t.Errorf("unexpected diff (-want +got):\n%s", diff)
+	}
+
+	same, err := d.DiffImages("old", "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(same) != 0 {
+		t.Errorf("expected no differences between an image and itself, got %v", same)
+	}
+
+	if _, err := d.DiffImages("old", "gone"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing image, got %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// ImageWithUsage is an image managed by nitric, along with the containers currently using it
+type ImageWithUsage struct {
+	ID      string
+	Tags    []string
+	Stack   string
+	Size    int64
+	Created time.Time
+	InUse   bool
+	// IDs of all containers (running or not) created from the image
+	ContainerIDs []string
+	// platform the image was built for, distinguishing variants of the same tag built for different architectures
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// Platform returns the image's platform in os/arch[/variant] form, e.g. linux/arm64/v8
+func (i *ImageWithUsage) Platform() string {
+	if i.Variant != "" {
+		return fmt.Sprintf("%s/%s/%s", i.OS, i.Architecture, i.Variant)
+	}
+
+	return fmt.Sprintf("%s/%s", i.OS, i.Architecture)
+}
+
+// isNitricImage reports whether the image was labeled by nitric when built
+func isNitricImage(labels map[string]string) bool {
+	_, hasStack := labels[StackLabel]
+	_, hasContentHash := labels[ContentHashLabel]
+
+	return hasStack || hasContentHash
+}
+
+// legacyImageStack identifies images built by nitric before labeling was introduced from their tags, returning the stack
+// they belong to. Service and batch images are tagged <stack>_<path> without a registry or namespace, only tags prefixed with
+// one of the provided stack names are accepted, as other images may follow the same naming.
+func legacyImageStack(repoTags, stackNames []string) (string, bool) {
+	for _, tag := range repoTags {
+		repository, _, _ := strings.Cut(tag, ":")
+		if strings.Contains(repository, "/") {
+			continue
+		}
+
+		for _, stackName := range stackNames {
+			if stackName != "" && strings.HasPrefix(repository, strings.ToLower(stackName)+"_") {
+				return stackName, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// ListAllImages lists the nitric managed images across all stacks, annotated with the containers using them.
+// Unlabeled images built by older versions are only listed for the provided stack names, see legacyImageStack.
+func (d *Docker) ListAllImages(legacyStackNames ...string) ([]ImageWithUsage, error) {
+	summaries, err := d.Client.ImageList(context.Background(), types.ImageListOptions{All: false})
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := d.Client.ContainerList(context.Background(), container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	containerIDsByImage := map[string][]string{}
+	for _, con := range containers {
+		containerIDsByImage[con.ImageID] = append(containerIDsByImage[con.ImageID], con.ID)
+	}
+
+	images := []ImageWithUsage{}
+
+	for _, summary := range summaries {
+		stack := summary.Labels[StackLabel]
+
+		if !isNitricImage(summary.Labels) {
+			legacyStack, ok := legacyImageStack(summary.RepoTags, legacyStackNames)
+			if !ok {
+				continue
+			}
+
+			stack = legacyStack
+		}
+
+		containerIDs := containerIDsByImage[summary.ID]
+
+		// image summaries don't include the platform
+		info, _, err := d.Client.ImageInspectWithRaw(context.Background(), summary.ID)
+		if err != nil && !client.IsErrNotFound(err) {
+			return nil, fmt.Errorf("unable to inspect image %s: %w", summary.ID, err)
+		}
+
+		images = append(images, ImageWithUsage{
+			ID:           summary.ID,
+			Tags:         summary.RepoTags,
+			Stack:        stack,
+			Size:         summary.Size,
+			Created:      time.Unix(summary.Created, 0),
+			InUse:        len(containerIDs) > 0,
+			ContainerIDs: containerIDs,
+			OS:           info.Os,
+			Architecture: info.Architecture,
+			Variant:      info.Variant,
+		})
+	}
+
+	return images, nil
+}
+
+// ErrSharedSizeUnknown is returned by ImageSizeBreakdown when the daemon doesn't report how much of an image is shared
+var ErrSharedSizeUnknown = errors.New("shared size of image unknown")
+
+// ImageSizeBreakdown returns the total size of an image, the bytes it shares with other images (e.g. common base layers)
+// and the bytes unique to it, which are reclaimed when the image is removed. When the daemon hasn't calculated the shared size
+// ErrSharedSizeUnknown is returned with only the total size, the image's layers may or may not be shared.
+func (d *Docker) ImageSizeBreakdown(idOrTag string) (total, shared, unique int64, err error) {
+	inspect, _, err := d.Client.ImageInspectWithRaw(context.Background(), idOrTag)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("unable to inspect image %s: %w", idOrTag, err)
+	}
+
+	usage, err := d.Client.DiskUsage(context.Background(), types.DiskUsageOptions{Types: []types.DiskUsageObject{types.ImageObject}})
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("unable to retrieve docker disk usage: %w", err)
+	}
+
+	for _, summary := range usage.Images {
+		if summary.ID != inspect.ID {
+			continue
+		}
+
+		// shared size is -1 when the daemon hasn't calculated it
+		if summary.SharedSize < 0 {
+			return summary.Size, 0, 0, fmt.Errorf("image %s: %w", idOrTag, ErrSharedSizeUnknown)
+		}
+
+		return summary.Size, summary.SharedSize, summary.Size - summary.SharedSize, nil
+	}
+
+	return 0, 0, 0, fmt.Errorf("image %s not found in docker disk usage", idOrTag)
+}
+
+// ImageExists reports whether an image is present locally
+func (d *Docker) ImageExists(image string) (bool, error) {
+	_, _, err := d.Client.ImageInspectWithRaw(context.Background(), image)
+	if client.IsErrNotFound(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("unable to inspect image %s: %w", image, err)
+	}
+
+	return true, nil
+}
+
+// PullPolicy controls when an image is pulled before a container is created from it
+type PullPolicy string
+
+const (
+	// PullAlways pulls the image every time, picking up changes to remote tags
+	PullAlways PullPolicy = "always"
+	// PullMissing pulls the image only when it isn't present locally
+	PullMissing PullPolicy = "missing"
+	// PullNever never pulls the image, failing if it isn't present locally
+	PullNever PullPolicy = "never"
+)
+
+// PullWithPolicy ensures an image is available locally according to the pull policy, pulling it if required
+func (d *Docker) PullWithPolicy(image string, policy PullPolicy, logs ...io.Writer) error {
+	switch policy {
+	case PullAlways:
+		return d.ImagePull(image, types.ImagePullOptions{}, logs...)
+	case PullMissing, PullNever:
+		exists, err := d.ImageExists(image)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return nil
+		}
+
+		if policy == PullNever {
+			return fmt.Errorf("image %s not found locally and the pull policy is %s: %w", image, policy, ErrNotFound)
+		}
+
+		return d.ImagePull(image, types.ImagePullOptions{}, logs...)
+	default:
+		return fmt.Errorf("unknown pull policy %q, expected one of %s, %s or %s", policy, PullAlways, PullMissing, PullNever)
+	}
+}
+
+// ImageTags returns every tag currently pointing at an image, excluding the <none>:<none> placeholder of untagged images
+func (d *Docker) ImageTags(idOrTag string) ([]string, error) {
+	info, _, err := d.Client.ImageInspectWithRaw(context.Background(), idOrTag)
+	if client.IsErrNotFound(err) {
+		return nil, fmt.Errorf("image %s: %w", idOrTag, ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to inspect image %s: %w", idOrTag, err)
+	}
+
+	tags := []string{}
+
+	for _, tag := range info.RepoTags {
+		if tag != "<none>:<none>" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, nil
+}
+
+// loadedImages reads the references reported by an image load stream, e.g. "Loaded image: app:latest" or, for untagged images,
+// "Loaded image ID: sha256:..."
+func loadedImages(rd io.Reader) ([]string, error) {
+	loaded := []string{}
+
+	decoder := json.NewDecoder(rd)
+
+	for {
+		line := Line{}
+		if err := decoder.Decode(&line); err == io.EOF {
+			return loaded, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to read image load output: %w", err)
+		}
+
+		if err := line.err(); err != nil {
+			return nil, err
+		}
+
+		for _, prefix := range []string{"Loaded image ID: ", "Loaded image: "} {
+			if ref, found := strings.CutPrefix(strings.TrimSpace(line.Stream), prefix); found {
+				loaded = append(loaded, ref)
+				break
+			}
+		}
+	}
+}
+
+// LoadAndTag loads an image from a tar archive (as produced by `docker save`) and applies the new tags to it, returning the applied tags.
+// The archive must contain a single image, as the new tags can't otherwise be attributed.
+func (d *Docker) LoadAndTag(r io.Reader, newTags []string) ([]string, error) {
+	ctx := context.Background()
+
+	resp, err := d.Client.ImageLoad(ctx, r, true)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load image: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	loaded, err := loadedImages(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// the tags of a single image are reported separately, resolve them to distinct image IDs
+	imageIDs := []string{}
+
+	for _, ref := range loaded {
+		info, _, err := d.Client.ImageInspectWithRaw(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("unable to inspect loaded image %s: %w", ref, err)
+		}
+
+		if !slices.Contains(imageIDs, info.ID) {
+			imageIDs = append(imageIDs, info.ID)
+		}
+	}
+
+	if len(imageIDs) != 1 {
+		return nil, fmt.Errorf("expected the archive to contain a single image, found %d", len(imageIDs))
+	}
+
+	tagged := []string{}
+
+	for _, tag := range newTags {
+		if err := d.Client.ImageTag(ctx, imageIDs[0], tag); err != nil {
+			return tagged, fmt.Errorf("unable to tag image %s as %s: %w", imageIDs[0], tag, err)
+		}
+
+		tagged = append(tagged, tag)
+	}
+
+	return tagged, nil
+}
+
+// ImageConfig is the runtime configuration an image declares, used as the defaults of containers created from it
+type ImageConfig struct {
+	Entrypoint []string
+	Cmd        []string
+	// environment variables in KEY=value form
+	Env []string
+	// exposed ports with their protocol, e.g. "8080/tcp", sorted
+	ExposedPorts []string
+	WorkingDir   string
+	User         string
+	Labels       map[string]string
+}
+
+// ImageConfig returns the entrypoint, command, environment and exposed ports declared by an image.
+// Images without a config (e.g. some imported images) return an empty config.
+func (d *Docker) ImageConfig(idOrTag string) (ImageConfig, error) {
+	info, _, err := d.Client.ImageInspectWithRaw(context.Background(), idOrTag)
+	if client.IsErrNotFound(err) {
+		return ImageConfig{}, fmt.Errorf("image %s: %w", idOrTag, ErrNotFound)
+	}
+
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("unable to inspect image %s: %w", idOrTag, err)
+	}
+
+	config := ImageConfig{
+		Entrypoint:   []string{},
+		Cmd:          []string{},
+		Env:          []string{},
+		ExposedPorts: []string{},
+		Labels:       map[string]string{},
+	}
+
+	if info.Config == nil {
+		return config, nil
+	}
+
+	config.Entrypoint = append(config.Entrypoint, info.Config.Entrypoint...)
+	config.Cmd = append(config.Cmd, info.Config.Cmd...)
+	config.Env = append(config.Env, info.Config.Env...)
+	config.WorkingDir = info.Config.WorkingDir
+	config.User = info.Config.User
+
+	for port := range info.Config.ExposedPorts {
+		config.ExposedPorts = append(config.ExposedPorts, string(port))
+	}
+
+	sort.Strings(config.ExposedPorts)
+
+	for k, v := range info.Config.Labels {
+		config.Labels[k] = v
+	}
+
+	return config, nil
+}
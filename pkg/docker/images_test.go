@@ -0,0 +1,82 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+)
+
+func TestLegacyImageStack(t *testing.T) {
+	stackNames := []string{"my-stack"}
+
+	tests := []struct {
+		name      string
+		tags      []string
+		wantStack string
+		wantOk    bool
+	}{
+		{name: "service image", tags: []string{"my-stack_services-api:latest"}, wantStack: "my-stack", wantOk: true},
+		{name: "image of another stack", tags: []string{"other-stack_services-api:latest"}},
+		{name: "user image with an underscore", tags: []string{"my_app:latest"}},
+		{name: "user migrations image", tags: []string{"foo-migrations:latest"}},
+		{name: "registry image", tags: []string{"docker.io/library/my-stack_api:latest"}},
+		{name: "unrelated image", tags: []string{"postgres:16"}},
+		{name: "untagged image"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stack, ok := legacyImageStack(tt.tags, stackNames)
+			if stack != tt.wantStack || ok != tt.wantOk {
+				t.Errorf("legacyImageStack(%v) = %q, %v, want %q, %v", tt.tags, stack, ok, tt.wantStack, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestImageSizeBreakdownUnknownSharedSize(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/images/api/json"):
+			_ = json.NewEncoder(w).Encode(types.ImageInspect{ID: "sha256:api"})
+		case strings.HasSuffix(r.URL.Path, "/system/df"):
+			_ = json.NewEncoder(w).Encode(types.DiskUsage{Images: []*image.Summary{{ID: "sha256:api", Size: 1000, SharedSize: -1}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	total, _, unique, err := d.ImageSizeBreakdown("api")
+	if !errors.Is(err, ErrSharedSizeUnknown) {
+		t.Fatalf("expected ErrSharedSizeUnknown, got %v", err)
+	}
+
+	if total != 1000 || unique != 0 {
+		t.Errorf("expected only the total size to be reported, got total %d and unique %d", total, unique)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/api/types/versions"
+
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+type EngineType string
+
+const (
+	EngineType_Docker EngineType = "docker"
+	EngineType_Podman EngineType = "podman"
+)
+
+// EngineCapabilities describes the features supported by the container engine
+type EngineCapabilities struct {
+	// SupportsBuildKit is true when builds can use BuildKit through docker buildx
+	SupportsBuildKit bool
+	Rootless         bool
+	Experimental     bool
+	// ContainerdImageStore is true when the daemon stores images with containerd, which keeps image indexes and attestations
+	ContainerdImageStore bool
+	// APIVersion is the API version used by this client, negotiated with the engine unless pinned with WithAPIVersion
+	APIVersion string
+	// MinAPIVersion and MaxAPIVersion are the range of API versions supported by the engine
+	MinAPIVersion string
+	MaxAPIVersion string
+}
+
+// EngineInfo describes the container engine the client is connected to
+type EngineInfo struct {
+	Type          EngineType
+	ServerVersion string
+	Capabilities  EngineCapabilities
+}
+
+// Info returns the type of the container engine and the features it supports
+func (d *Docker) Info() (*EngineInfo, error) {
+	version, err := d.Client.ServerVersion(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve engine version: %w", err)
+	}
+
+	info, err := d.Client.Info(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve engine info: %w", err)
+	}
+
+	engineType := EngineType_Docker
+
+	// podman's docker compatible API identifies itself through its components
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), "podman") {
+			engineType = EngineType_Podman
+		}
+	}
+
+	rootless := false
+
+	for _, opt := range info.SecurityOptions {
+		if strings.Contains(opt, "name=rootless") {
+			rootless = true
+		}
+	}
+
+	return &EngineInfo{
+		Type:          engineType,
+		ServerVersion: version.Version,
+		Capabilities: EngineCapabilities{
+			SupportsBuildKit:     engineType == EngineType_Docker && tui.DockerBuildxAvailable() == nil,
+			Rootless:             rootless,
+			Experimental:         version.Experimental,
+			ContainerdImageStore: usesContainerdImageStore(info),
+			APIVersion:           d.Client.ClientVersion(),
+			MinAPIVersion:        version.MinAPIVersion,
+			MaxAPIVersion:        version.APIVersion,
+		},
+	}, nil
+}
+
+// usesContainerdImageStore reports whether the daemon stores images with containerd rather than a graph driver,
+// in which case its driver status reports a containerd snapshotter as the driver type
+func usesContainerdImageStore(info system.Info) bool {
+	for _, status := range info.DriverStatus {
+		if status[0] == "driver-type" && strings.Contains(status[1], "io.containerd.snapshotter") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ErrUnsupportedAPIVersion is returned by RequireMinVersion when the engine's API is older than an operation requires
+var ErrUnsupportedAPIVersion = errors.New("unsupported docker API version")
+
+// RequireMinVersion ensures the API version negotiated with the engine (or pinned with WithAPIVersion) is at least apiVersion,
+// e.g. "1.40" for device requests. Operations that need a newer engine should call it first, so an old engine is reported
+// clearly rather than as a failed request.
+func (d *Docker) RequireMinVersion(apiVersion string) error {
+	// negotiation happens on first use, ensure the version reflects the engine
+	d.Client.NegotiateAPIVersion(context.Background())
+
+	current := d.Client.ClientVersion()
+	if versions.LessThan(current, apiVersion) {
+		return fmt.Errorf("%w: this operation needs Docker API >= %s; you have %s, upgrade docker to continue", ErrUnsupportedAPIVersion, apiVersion, current)
+	}
+
+	return nil
+}
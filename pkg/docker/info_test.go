@@ -0,0 +1,63 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/system"
+)
+
+func TestRequireMinVersion(t *testing.T) {
+	daemon := httptest.NewServer(http.NotFoundHandler())
+	defer daemon.Close()
+
+	// the test client is pinned to API 1.44
+	d := newTestDocker(t, daemon)
+
+	for _, version := range []string{"1.40", "1.44"} {
+		if err := d.RequireMinVersion(version); err != nil {
+			t.Errorf("RequireMinVersion(%s) = %v, expected no error", version, err)
+		}
+	}
+
+	err := d.RequireMinVersion("1.45")
+	if !errors.Is(err, ErrUnsupportedAPIVersion) {
+		t.Fatalf("RequireMinVersion(1.45) = %v, expected ErrUnsupportedAPIVersion", err)
+	}
+
+	if !strings.Contains(err.Error(), "needs Docker API >= 1.45; you have 1.44") {
+		t.Errorf("expected the required and current versions to be reported, got %v", err)
+	}
+}
+
+func TestUsesContainerdImageStore(t *testing.T) {
+	containerd := system.Info{DriverStatus: [][2]string{{"driver-type", "io.containerd.snapshotter.v1"}}}
+	overlay := system.Info{DriverStatus: [][2]string{{"Backing Filesystem", "extfs"}, {"Supports d_type", "true"}}}
+
+	if !usesContainerdImageStore(containerd) {
+		t.Error("expected the containerd snapshotter to be detected")
+	}
+
+	if usesContainerdImageStore(overlay) {
+		t.Error("expected the overlay2 graph driver not to be detected as the containerd image store")
+	}
+}
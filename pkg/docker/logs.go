@@ -0,0 +1,222 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// LogOptions limits which container logs are read, the zero value reads all logs without following
+type LogOptions struct {
+	// Tail is the number of lines to read from the end of the logs, e.g. "100". Empty reads all lines.
+	Tail string
+	// Since only reads logs written after this time. The zero time reads from the start.
+	Since time.Time
+	// Follow keeps streaming new logs until the context is cancelled or the container stops
+	Follow bool
+	// Timestamps prefixes each line with the time it was written
+	Timestamps bool
+}
+
+func (o LogOptions) toContainerLogsOptions() container.LogsOptions {
+	opts := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       o.Tail,
+		Follow:     o.Follow,
+		Timestamps: o.Timestamps,
+	}
+
+	if !o.Since.IsZero() {
+		opts.Since = o.Since.Format(time.RFC3339Nano)
+	}
+
+	return opts
+}
+
+// Logs reads a container's logs, writing stdout and stderr output to the respective writers
+func (d *Docker) Logs(ctx context.Context, nameOrID string, opts LogOptions, stdout, stderr io.Writer) error {
+	info, err := d.Client.ContainerInspect(ctx, nameOrID)
+	if err != nil {
+		return fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	logReader, err := d.Client.ContainerLogs(ctx, info.ID, opts.toContainerLogsOptions())
+	if err != nil {
+		return fmt.Errorf("unable to read logs for container %s: %w", nameOrID, err)
+	}
+
+	defer logReader.Close()
+
+	// logs of containers with a TTY aren't multiplexed
+	if info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(stdout, logReader)
+		return err
+	}
+
+	_, err = stdcopy.StdCopy(stdout, stderr, logReader)
+
+	return err
+}
+
+// DumpLogs writes all of a container's current logs to the writer, without following new output
+func (d *Docker) DumpLogs(nameOrID string, w io.Writer) error {
+	return d.Logs(context.Background(), nameOrID, LogOptions{}, w, w)
+}
+
+// dumpLogsToDir writes a container's logs to a file named after the container in dir
+func (d *Docker) dumpLogsToDir(con types.Container, dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create log directory %s: %w", dir, err)
+	}
+
+	name := con.ID
+	if len(con.Names) > 0 {
+		name = strings.TrimPrefix(con.Names[0], "/")
+	}
+
+	logFile, err := os.Create(filepath.Join(dir, fmt.Sprintf("%s.log", name)))
+	if err != nil {
+		return fmt.Errorf("unable to create log file for container %s: %w", name, err)
+	}
+
+	defer logFile.Close()
+
+	return d.DumpLogs(con.ID, logFile)
+}
+
+// LogEntry is a single line of container output
+type LogEntry struct {
+	// Stream is "stdout" or "stderr", containers with a TTY only write to stdout
+	Stream    string
+	Timestamp time.Time
+	Message   string
+}
+
+// logFrameHeaderSize is the size of the header docker prepends to each frame of multiplexed (non-TTY) log output:
+// the stream type, three zero bytes and the big endian length of the frame
+const logFrameHeaderSize = 8
+
+// parseLogLine splits the timestamp docker prefixes a log line with from its message
+func parseLogLine(stream, line string) LogEntry {
+	entry := LogEntry{Stream: stream, Message: line}
+
+	if timestamp, message, found := strings.Cut(line, " "); found {
+		if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+			entry.Timestamp = t
+			entry.Message = message
+		}
+	}
+
+	return entry
+}
+
+// readLogEntries reads timestamped log output, demultiplexing stdout and stderr frames unless the container has a TTY,
+// sending each line to entries until the output ends or the context is cancelled
+func readLogEntries(ctx context.Context, rd io.Reader, tty bool, entries chan<- LogEntry) error {
+	send := func(stream string, payload []byte) bool {
+		for _, line := range strings.Split(strings.TrimSuffix(string(payload), "\n"), "\n") {
+			select {
+			case entries <- parseLogLine(stream, line):
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if tty {
+		scanner := bufio.NewScanner(rd)
+		for scanner.Scan() {
+			if !send("stdout", scanner.Bytes()) {
+				return ctx.Err()
+			}
+		}
+
+		return scanner.Err()
+	}
+
+	header := make([]byte, logFrameHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(rd, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		stream := "stdout"
+		if header[0] == byte(stdcopy.Stderr) {
+			stream = "stderr"
+		}
+
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(rd, payload); err != nil {
+			return err
+		}
+
+		// the stdin stream type is only used by docker for errors reading the container's logs
+		if header[0] == byte(stdcopy.Systemerr) {
+			return fmt.Errorf("error reading logs: %s", payload)
+		}
+
+		if !send(stream, payload) {
+			return ctx.Err()
+		}
+	}
+}
+
+// StructuredLogs streams a container's logs as entries, identifying whether each line was written to stdout or stderr.
+// The channel is closed when the logs end (or, when following, the container stops), the context is cancelled or reading fails.
+func (d *Docker) StructuredLogs(ctx context.Context, nameOrID string, opts LogOptions) (<-chan LogEntry, error) {
+	info, err := d.Client.ContainerInspect(ctx, nameOrID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	// timestamps are always requested to populate the entries
+	opts.Timestamps = true
+
+	logReader, err := d.Client.ContainerLogs(ctx, info.ID, opts.toContainerLogsOptions())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read logs for container %s: %w", nameOrID, err)
+	}
+
+	entries := make(chan LogEntry)
+
+	go func() {
+		defer close(entries)
+		defer logReader.Close()
+
+		_ = readLogEntries(ctx, logReader, info.Config != nil && info.Config.Tty, entries)
+	}()
+
+	return entries, nil
+}
@@ -0,0 +1,60 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadLogEntries(t *testing.T) {
+	output := &bytes.Buffer{}
+	stdout := stdcopy.NewStdWriter(output, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(output, stdcopy.Stderr)
+
+	_, _ = stdout.Write([]byte("2024-05-01T10:00:00.000000001Z listening on :8080\n"))
+	_, _ = stderr.Write([]byte("2024-05-01T10:00:01Z panic: connection refused\n"))
+	_, _ = stdout.Write([]byte("no timestamp\n"))
+
+	entries := make(chan LogEntry, 10)
+
+	if err := readLogEntries(context.Background(), output, false, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	close(entries)
+
+	got := []LogEntry{}
+	for entry := range entries {
+		got = append(got, entry)
+	}
+
+	want := []LogEntry{
+		{Stream: "stdout", Timestamp: time.Date(2024, 5, 1, 10, 0, 0, 1, time.UTC), Message: "listening on :8080"},
+		{Stream: "stderr", Timestamp: time.Date(2024, 5, 1, 10, 0, 1, 0, time.UTC), Message: "panic: connection refused"},
+		{Stream: "stdout", Message: "no timestamp"},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+}
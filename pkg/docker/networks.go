@@ -0,0 +1,251 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/samber/lo"
+)
+
+// NetworkOptions configures a network created with NetworkCreateWithOptions
+type NetworkOptions struct {
+	// Driver defaults to bridge when empty
+	Driver string
+	// Subnet in CIDR form, e.g. 172.28.0.0/16
+	Subnet string
+	// Gateway for the subnet, only used when Subnet is set
+	Gateway    string
+	Attachable bool
+	Internal   bool
+	Labels     map[string]string
+	// EnableIPv6 creates a dual-stack network, IPv6Subnet is required when enabled
+	EnableIPv6  bool
+	IPv6Subnet  string
+	IPv6Gateway string
+	// DriverOptions are passed to the network driver, e.g. "com.docker.network.driver.mtu" or, for podman's netavark/CNI backends,
+	// "isolate" and "mtu"
+	DriverOptions map[string]string
+}
+
+// NetworkCreate creates a bridge network for a stack with default options, returning the ID of the network.
+// The network is labeled with the stack (see StackLabel), so TeardownStack removes it.
+// If a network with the same name already exists its ID is returned instead.
+func (d *Docker) NetworkCreate(name, stackName string) (string, error) {
+	return d.NetworkCreateWithOptions(name, NetworkOptions{Labels: map[string]string{StackLabel: stackName}})
+}
+
+// NetworkCreateWithOptions creates a network with the provided options, returning the ID of the network.
+// If a network with the same name already exists its ID is returned and the options are ignored.
+func (d *Docker) NetworkCreateWithOptions(name string, opts NetworkOptions) (string, error) {
+	existing, err := d.Client.NetworkInspect(context.Background(), name, types.NetworkInspectOptions{})
+	if err == nil {
+		return existing.ID, nil
+	}
+
+	if !client.IsErrNotFound(err) {
+		return "", fmt.Errorf("unable to inspect network %s: %w", name, err)
+	}
+
+	driver := opts.Driver
+	if driver == "" {
+		driver = "bridge"
+	}
+
+	if opts.EnableIPv6 && opts.IPv6Subnet == "" {
+		return "", fmt.Errorf("unable to create network %s: an IPv6 subnet is required to enable IPv6", name)
+	}
+
+	// only drivers other than bridge are restricted by rootless engines, avoid looking up the engine otherwise
+	if driver != "bridge" {
+		info, err := d.Info()
+		if err != nil {
+			return "", err
+		}
+
+		if err := checkRootlessNetworkDriver(info, driver); err != nil {
+			return "", fmt.Errorf("unable to create network %s: %w", name, err)
+		}
+	}
+
+	createOpts := types.NetworkCreate{
+		Driver:     driver,
+		Attachable: opts.Attachable,
+		Internal:   opts.Internal,
+		Labels:     d.clientLabels(opts.Labels),
+		EnableIPv6: opts.EnableIPv6,
+		Options:    opts.DriverOptions,
+	}
+
+	ipamConfig := []network.IPAMConfig{}
+
+	if opts.Subnet != "" {
+		ipamConfig = append(ipamConfig, network.IPAMConfig{
+			Subnet:  opts.Subnet,
+			Gateway: opts.Gateway,
+		})
+	}
+
+	if opts.EnableIPv6 {
+		ipamConfig = append(ipamConfig, network.IPAMConfig{
+			Subnet:  opts.IPv6Subnet,
+			Gateway: opts.IPv6Gateway,
+		})
+	}
+
+	if len(ipamConfig) > 0 {
+		createOpts.IPAM = &network.IPAM{
+			Driver: "default",
+			Config: ipamConfig,
+		}
+	}
+
+	resp, err := d.Client.NetworkCreate(context.Background(), name, createOpts)
+	if errdefs.IsConflict(err) {
+		// a concurrent call created the network between our inspect and create
+		existing, inspectErr := d.Client.NetworkInspect(context.Background(), name, types.NetworkInspectOptions{})
+		if inspectErr != nil {
+			return "", fmt.Errorf("network %s already exists but could not be inspected: %w", name, inspectErr)
+		}
+
+		return existing.ID, nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("unable to create network %s: %w", name, err)
+	}
+
+	return resp.ID, nil
+}
+
+// GetContainerIP returns the IP address of a container on the named network, the IPv6 address is returned when ipv6 is true
+func (d *Docker) GetContainerIP(nameOrID, networkName string, ipv6 bool) (string, error) {
+	info, err := d.Client.ContainerInspect(context.Background(), nameOrID)
+	if err != nil {
+		return "", fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	if info.NetworkSettings == nil || info.NetworkSettings.Networks[networkName] == nil {
+		return "", fmt.Errorf("container %s is not attached to network %s", nameOrID, networkName)
+	}
+
+	endpoint := info.NetworkSettings.Networks[networkName]
+
+	ip := endpoint.IPAddress
+	if ipv6 {
+		ip = endpoint.GlobalIPv6Address
+	}
+
+	if ip == "" {
+		return "", fmt.Errorf("container %s has no address on network %s", nameOrID, networkName)
+	}
+
+	return ip, nil
+}
+
+// NetworkConnect connects a container to a network, reachable by peers on the network through the provided aliases as well as its name.
+// Docker can't change the aliases of an existing connection, so if the container is already connected it's reconnected with
+// its existing aliases plus the new ones.
+func (d *Docker) NetworkConnect(networkName, nameOrID string, aliases ...string) error {
+	ctx := context.Background()
+
+	info, err := d.Client.ContainerInspect(ctx, nameOrID)
+	if err != nil {
+		return fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	if info.NetworkSettings != nil {
+		if endpoint, connected := info.NetworkSettings.Networks[networkName]; connected {
+			if lo.Every(endpoint.Aliases, aliases) {
+				return nil
+			}
+
+			aliases = lo.Uniq(append(endpoint.Aliases, aliases...))
+
+			if err := d.Client.NetworkDisconnect(ctx, networkName, info.ID, false); err != nil {
+				return fmt.Errorf("unable to disconnect container %s from network %s: %w", nameOrID, networkName, err)
+			}
+		}
+	}
+
+	err = d.Client.NetworkConnect(ctx, networkName, info.ID, &network.EndpointSettings{Aliases: aliases})
+	if err != nil {
+		return fmt.Errorf("unable to connect container %s to network %s: %w", nameOrID, networkName, err)
+	}
+
+	return nil
+}
+
+// NetworkEndpoint describes a container's connection to a network
+type NetworkEndpoint struct {
+	NetworkID   string
+	IPAddress   string
+	IPv6Address string
+	Gateway     string
+	MacAddress  string
+	Aliases     []string
+}
+
+// ContainerNetworks returns the networks a container is connected to, keyed by network name, returns ErrNotFound if the container doesn't exist
+func (d *Docker) ContainerNetworks(nameOrID string) (map[string]NetworkEndpoint, error) {
+	info, err := d.Client.ContainerInspect(context.Background(), nameOrID)
+	if client.IsErrNotFound(err) {
+		return nil, fmt.Errorf("container %s: %w", nameOrID, ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	endpoints := map[string]NetworkEndpoint{}
+
+	if info.NetworkSettings == nil {
+		return endpoints, nil
+	}
+
+	for networkName, endpoint := range info.NetworkSettings.Networks {
+		if endpoint == nil {
+			continue
+		}
+
+		endpoints[networkName] = NetworkEndpoint{
+			NetworkID:   endpoint.NetworkID,
+			IPAddress:   endpoint.IPAddress,
+			IPv6Address: endpoint.GlobalIPv6Address,
+			Gateway:     endpoint.Gateway,
+			MacAddress:  endpoint.MacAddress,
+			Aliases:     endpoint.Aliases,
+		}
+	}
+
+	return endpoints, nil
+}
+
+// NetworksByLabel returns the networks matching every provided label, limited to the client's namespace when it has a name prefix
+func (d *Docker) NetworksByLabel(labels map[string]string) ([]types.NetworkResource, error) {
+	networks, err := d.Client.NetworkList(context.Background(), types.NetworkListOptions{Filters: d.labelFilters(labels)})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list networks: %w", err)
+	}
+
+	return networks, nil
+}
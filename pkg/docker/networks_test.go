@@ -0,0 +1,171 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newRacingNetworkDaemon fakes a docker daemon where concurrent callers all see the network as missing,
+// then race to create it, with only the first create succeeding
+func newRacingNetworkDaemon(t *testing.T, callers int) *httptest.Server {
+	var (
+		lock            sync.Mutex
+		created         bool
+		missingInspects int
+	)
+
+	allInspected := make(chan struct{})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/networks/create"):
+			lock.Lock()
+			defer lock.Unlock()
+
+			if created {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, `{"message":"network with name test-net already exists"}`)
+
+				return
+			}
+
+			created = true
+
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"Id":"test-net-id"}`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/networks/test-net"):
+			lock.Lock()
+
+			if created {
+				lock.Unlock()
+				fmt.Fprint(w, `{"Id":"test-net-id","Name":"test-net"}`)
+
+				return
+			}
+
+			missingInspects++
+			if missingInspects == callers {
+				close(allInspected)
+			}
+
+			lock.Unlock()
+
+			// hold the response until every caller has inspected, forcing them all to attempt a create
+			<-allInspected
+
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"network test-net not found"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+}
+
+func TestNetworkCreateConcurrent(t *testing.T) {
+	const callers = 2
+
+	daemon := newRacingNetworkDaemon(t, callers)
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	ids := make([]string, callers)
+	errs := make([]error, callers)
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			ids[i], errs[i] = d.NetworkCreate("test-net", "test")
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil {
+			t.Errorf("NetworkCreate() caller %d error = %v", i, errs[i])
+		}
+
+		if ids[i] != "test-net-id" {
+			t.Errorf("NetworkCreate() caller %d id = %q, want %q", i, ids[i], "test-net-id")
+		}
+	}
+}
+
+// TestNetworkConnectAddsAliases checks that adding an alias to an existing connection reconnects the container with both the
+// existing and new aliases, as peers resolve services by alias
+func TestNetworkConnectAddsAliases(t *testing.T) {
+	requests := []string{}
+	var connectBody types.NetworkConnect
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/api/json"):
+			fmt.Fprint(w, `{"Id":"api-id","Name":"/api","NetworkSettings":{"Networks":{"test-net":{"Aliases":["api"]}}}}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/networks/test-net/disconnect"):
+			requests = append(requests, "disconnect")
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/networks/test-net/connect"):
+			requests = append(requests, "connect")
+
+			if err := json.NewDecoder(r.Body).Decode(&connectBody); err != nil {
+				t.Error(err)
+			}
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+
+	if err := d.NetworkConnect("test-net", "api", "svc"); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"disconnect", "connect"}, requests); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+
+	if connectBody.EndpointConfig == nil {
+		t.Fatal("expected the connection to have an endpoint config")
+	}
+
+	if diff := cmp.Diff([]string{"api", "svc"}, connectBody.EndpointConfig.Aliases); diff != "" {
+		t.Errorf("aliases mismatch (-want +got):\n%s", diff)
+	}
+}
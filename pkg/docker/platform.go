@@ -0,0 +1,112 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// buildPlatform is the platform images are built for, matching the cloud runtimes nitric deploys to
+const buildPlatform = "linux/amd64"
+
+// normalizeArch maps architecture names reported by the engine (uname style) to their OCI platform equivalents
+func normalizeArch(arch string) string {
+	switch strings.ToLower(arch) {
+	case "x86_64", "x86-64", "amd64":
+		return "amd64"
+	case "aarch64", "arm64":
+		return "arm64"
+	case "armhf", "armv7l", "arm":
+		return "arm"
+	case "i386", "i686", "386":
+		return "386"
+	default:
+		return strings.ToLower(arch)
+	}
+}
+
+// builderPlatforms returns the platforms a buildx builder can build for, including those emulated with QEMU
+func builderPlatforms(builderName string) ([]string, error) {
+	output := &bytes.Buffer{}
+
+	cmd := exec.Command("docker", "buildx", "inspect", "--bootstrap", builderName)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("unable to inspect builder %s: %w: %s", builderName, err, output.String())
+	}
+
+	platforms := []string{}
+
+	// e.g. "Platforms: linux/amd64, linux/amd64/v2, linux/arm64*"
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		list, found := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "Platforms:")
+		if !found {
+			continue
+		}
+
+		for _, platform := range strings.Split(list, ",") {
+			platforms = append(platforms, strings.TrimSuffix(strings.TrimSpace(platform), "*"))
+		}
+	}
+
+	return platforms, nil
+}
+
+// SupportsPlatform reports whether images can be built for a platform (e.g. linux/arm64), either natively by the engine or
+// through QEMU emulation registered with binfmt_misc on the nitric buildx builder
+func (d *Docker) SupportsPlatform(platform string) (bool, error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return false, fmt.Errorf("invalid platform %s, expected os/arch[/variant]", platform)
+	}
+
+	info, err := d.Client.Info(context.Background())
+	if err != nil {
+		return false, fmt.Errorf("unable to retrieve engine info: %w", err)
+	}
+
+	if strings.EqualFold(info.OSType, parts[0]) && normalizeArch(info.Architecture) == normalizeArch(parts[1]) {
+		return true, nil
+	}
+
+	platforms, err := builderPlatforms("nitric")
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range platforms {
+		if strings.EqualFold(p, platform) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// unsupportedPlatformError explains how to enable emulation for a platform the host can't build for
+func unsupportedPlatformError(platform string) error {
+	return fmt.Errorf("this host can't build images for %s, enable emulation by registering QEMU with binfmt_misc, "+
+		"e.g. `docker run --privileged --rm tonistiigi/binfmt --install all`, or enable it in Docker Desktop's settings", platform)
+}
@@ -0,0 +1,184 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// classic builder steps, e.g. "Step 3/10 : RUN make"
+	classicStepPattern = regexp.MustCompile(`^Step (\d+)/(\d+) :`)
+	// BuildKit plain progress steps, e.g. "#7 [build 3/10] RUN make" or "#7 [3/10] RUN make"
+	buildkitStepPattern = regexp.MustCompile(`^#\d+ \[(?:[^\]]* )?(\d+)/(\d+)\]`)
+)
+
+// stepPercent estimates build progress from a line of build output that reports a step number, e.g. "Step 3/10"
+func stepPercent(line []byte) (int, bool) {
+	matches := classicStepPattern.FindSubmatch(line)
+	if matches == nil {
+		matches = buildkitStepPattern.FindSubmatch(line)
+	}
+
+	if matches == nil {
+		return 0, false
+	}
+
+	step, _ := strconv.Atoi(string(matches[1]))
+	total, _ := strconv.Atoi(string(matches[2]))
+
+	if total == 0 || step > total {
+		return 0, false
+	}
+
+	// a step is reported as it starts, so count the previous step as complete
+	return (step - 1) * 100 / total, true
+}
+
+// lineWriter buffers written output, passing each complete line (without its line ending) to onLine
+type lineWriter struct {
+	lock   sync.Mutex
+	buffer []byte
+	onLine func(line []byte)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.buffer = append(w.buffer, p...)
+
+	for {
+		idx := bytes.IndexByte(w.buffer, '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := bytes.TrimSuffix(w.buffer[:idx], []byte("\r"))
+		w.buffer = w.buffer[idx+1:]
+
+		w.onLine(line)
+	}
+
+	return len(p), nil
+}
+
+// flush passes any remaining partial line to onLine
+func (w *lineWriter) flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(w.buffer) > 0 {
+		w.onLine(w.buffer)
+		w.buffer = nil
+	}
+}
+
+// timestampWriter prefixes each line written with the time elapsed since start
+type timestampWriter struct {
+	lineWriter
+	next  io.Writer
+	start time.Time
+}
+
+func newTimestampWriter(next io.Writer, start time.Time) *timestampWriter {
+	w := &timestampWriter{next: next, start: start}
+	w.onLine = w.line
+
+	return w
+}
+
+// line is called with the lineWriter lock held
+func (w *timestampWriter) line(line []byte) {
+	fmt.Fprintf(w.next, "[%6.1fs] %s\n", time.Since(w.start).Seconds(), line)
+}
+
+// quietOutputLines is the number of lines of build output kept by quiet builds, to explain a failure
+const quietOutputLines = 50
+
+// tailWriter keeps the last lines written, discarding the rest
+type tailWriter struct {
+	lineWriter
+	max  int
+	tail []string
+}
+
+func newTailWriter(max int) *tailWriter {
+	w := &tailWriter{max: max}
+	w.onLine = w.line
+
+	return w
+}
+
+// line is called with the lineWriter lock held
+func (w *tailWriter) line(line []byte) {
+	w.tail = append(w.tail, string(line))
+
+	if len(w.tail) > w.max {
+		w.tail = w.tail[len(w.tail)-w.max:]
+	}
+}
+
+// lines returns the last lines written, including any incomplete final line
+func (w *tailWriter) lines() []string {
+	w.flush()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return append([]string{}, w.tail...)
+}
+
+// progressWriter is a best-effort build progress estimator, reporting a 0-100 percentage as build output is written.
+// Only increases are reported, as the steps of multi-stage builds are reported per stage.
+type progressWriter struct {
+	lineWriter
+	onProgress func(percent int)
+	percent    int
+}
+
+func newProgressWriter(onProgress func(percent int)) *progressWriter {
+	w := &progressWriter{onProgress: onProgress, percent: -1}
+	w.onLine = w.line
+
+	return w
+}
+
+// line is called with the lineWriter lock held
+func (w *progressWriter) line(line []byte) {
+	if percent, ok := stepPercent(bytes.TrimSpace(line)); ok && percent > w.percent {
+		w.percent = percent
+		w.onProgress(percent)
+	}
+}
+
+// complete reports the build as finished
+func (w *progressWriter) complete() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.percent < 100 {
+		w.percent = 100
+		w.onProgress(100)
+	}
+}
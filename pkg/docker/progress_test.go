@@ -0,0 +1,71 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProgressWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []int
+	}{
+		{
+			name:   "classic builder",
+			output: "Step 1/4 : FROM alpine\n ---> 05455a08881e\nStep 2/4 : COPY . .\nStep 3/4 : RUN make\nStep 4/4 : CMD [\"app\"]\n",
+			want:   []int{0, 25, 50, 75, 100},
+		},
+		{
+			name:   "buildkit multi-stage",
+			output: "#5 [build 1/2] FROM golang\n#6 [build 2/2] RUN go build\n#7 [stage-1 1/2] FROM alpine\n#8 [stage-1 2/2] COPY --from=build /app /app\n#8 DONE 0.1s\n",
+			want:   []int{0, 50, 100},
+		},
+		{
+			name:   "no steps",
+			output: "building...\n",
+			want:   []int{100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []int{}
+
+			w := newProgressWriter(func(percent int) {
+				got = append(got, percent)
+			})
+
+			// write in uneven chunks to exercise line buffering
+			for _, chunk := range strings.SplitAfter(tt.output, " ") {
+				if _, err := w.Write([]byte(chunk)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w.complete()
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("progress mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/go-units"
+
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+// PruneBuildCache removes build cache until at most keepBytes remain, returning the bytes reclaimed.
+// Both the daemon's BuildKit cache and the cache of the nitric buildx builder container are pruned, each prunes its own cache
+// independently so keepBytes is split evenly between them when the builder exists.
+func (d *Docker) PruneBuildCache(keepBytes int64) (int64, error) {
+	hasBuilder := tui.DockerBuildxAvailable() == nil && builderExists("nitric")
+
+	daemonKeepBytes := keepBytes
+	if hasBuilder {
+		daemonKeepBytes = keepBytes / 2
+	}
+
+	report, err := d.Client.BuildCachePrune(context.Background(), types.BuildCachePruneOptions{
+		All:         true,
+		KeepStorage: daemonKeepBytes,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to prune build cache: %w", err)
+	}
+
+	reclaimed := int64(report.SpaceReclaimed)
+
+	if !hasBuilder {
+		return reclaimed, nil
+	}
+
+	builderReclaimed, err := pruneBuilderCache("nitric", keepBytes-daemonKeepBytes)
+	if err != nil {
+		return reclaimed, err
+	}
+
+	return reclaimed + builderReclaimed, nil
+}
+
+// builderExists reports whether a buildx builder has been created
+func builderExists(builderName string) bool {
+	return exec.Command("docker", "buildx", "inspect", builderName).Run() == nil
+}
+
+// pruneBuilderCache prunes the cache of a buildx builder, returning the bytes reclaimed.
+// Builders that haven't been created yet have no cache to prune.
+func pruneBuilderCache(builderName string, keepBytes int64) (int64, error) {
+	if !builderExists(builderName) {
+		return 0, nil
+	}
+
+	output := &bytes.Buffer{}
+
+	cmd := exec.Command("docker", "buildx", "prune", "--builder", builderName, "--all", "--force", "--keep-storage", fmt.Sprint(keepBytes))
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("unable to prune cache of builder %s: %w: %s", builderName, err, output.String())
+	}
+
+	// the reclaimed total is reported as the last line, e.g. "Total:	1.2GB"
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		total, found := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "Total:")
+		if !found {
+			continue
+		}
+
+		size, err := units.FromHumanSize(strings.TrimSpace(total))
+		if err != nil {
+			return 0, nil
+		}
+
+		return size, nil
+	}
+
+	return 0, nil
+}
+
+// PruneOptions selects the resources removed by PruneAll, stopped containers, unused images and unused networks are always removed
+type PruneOptions struct {
+	// remove unused volumes, off by default as volumes hold data (e.g. local databases)
+	Volumes bool
+	// prune the cache of the nitric buildx builder, the daemon's shared build cache is never touched
+	BuildCache bool
+	// build cache to retain when pruning the build cache
+	KeepBuildCacheBytes int64
+}
+
+// PruneCategoryReport records the resources removed from a single category by PruneAll
+type PruneCategoryReport struct {
+	// IDs (or names, for networks and volumes) of the removed resources
+	Removed        []string
+	ReclaimedBytes int64
+}
+
+// PruneReport records the resources removed by PruneAll, by category
+type PruneReport struct {
+	Containers PruneCategoryReport
+	Images     PruneCategoryReport
+	Networks   PruneCategoryReport
+	Volumes    PruneCategoryReport
+	BuildCache PruneCategoryReport
+}
+
+// TotalReclaimedBytes returns the disk space reclaimed across all categories
+func (r *PruneReport) TotalReclaimedBytes() int64 {
+	return r.Containers.ReclaimedBytes + r.Images.ReclaimedBytes + r.Networks.ReclaimedBytes + r.Volumes.ReclaimedBytes + r.BuildCache.ReclaimedBytes
+}
+
+// PruneAll reclaims the space used by a stack: its stopped containers, unused images and networks, optionally its unused volumes,
+// and the nitric builder's cache. Only resources labeled with the stack (and the client's namespace, except images) are removed,
+// resources created outside of nitric are never touched. Pruning stops at the first failure, returning what was removed so far.
+func (d *Docker) PruneAll(stackName string, opts PruneOptions) (PruneReport, error) {
+	ctx := context.Background()
+	report := PruneReport{}
+	stackFilter := filters.NewArgs(filters.Arg("label", fmt.Sprintf("%s=%s", StackLabel, stackName)))
+
+	containers, err := d.Client.ContainersPrune(ctx, d.labelFilters(map[string]string{StackLabel: stackName}))
+	if err != nil {
+		return report, fmt.Errorf("unable to prune containers: %w", err)
+	}
+
+	report.Containers = PruneCategoryReport{Removed: containers.ContainersDeleted, ReclaimedBytes: int64(containers.SpaceReclaimed)}
+
+	// include tagged images, not only dangling ones
+	imageFilter := stackFilter.Clone()
+	imageFilter.Add("dangling", "false")
+
+	images, err := d.Client.ImagesPrune(ctx, imageFilter)
+	if err != nil {
+		return report, fmt.Errorf("unable to prune images: %w", err)
+	}
+
+	report.Images.ReclaimedBytes = int64(images.SpaceReclaimed)
+	for _, image := range images.ImagesDeleted {
+		if image.Deleted != "" {
+			report.Images.Removed = append(report.Images.Removed, image.Deleted)
+		}
+	}
+
+	networks, err := d.Client.NetworksPrune(ctx, d.labelFilters(map[string]string{StackLabel: stackName}))
+	if err != nil {
+		return report, fmt.Errorf("unable to prune networks: %w", err)
+	}
+
+	report.Networks.Removed = networks.NetworksDeleted
+
+	if opts.Volumes {
+		volumeFilter := d.labelFilters(map[string]string{StackLabel: stackName})
+
+		// since API 1.42 only anonymous volumes are pruned unless all is set
+		if versions.GreaterThanOrEqualTo(d.Client.ClientVersion(), "1.42") {
+			volumeFilter.Add("all", "true")
+		}
+
+		volumes, err := d.Client.VolumesPrune(ctx, volumeFilter)
+		if err != nil {
+			return report, fmt.Errorf("unable to prune volumes: %w", err)
+		}
+
+		report.Volumes = PruneCategoryReport{Removed: volumes.VolumesDeleted, ReclaimedBytes: int64(volumes.SpaceReclaimed)}
+	}
+
+	if opts.BuildCache && tui.DockerBuildxAvailable() == nil {
+		reclaimed, err := pruneBuilderCache("nitric", opts.KeepBuildCacheBytes)
+		if err != nil {
+			return report, err
+		}
+
+		report.BuildCache.ReclaimedBytes = reclaimed
+	}
+
+	return report, nil
+}
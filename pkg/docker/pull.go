@@ -0,0 +1,97 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultPullConcurrency is conservative, as concurrent pulls from the same registry count towards its rate limits
+const defaultPullConcurrency = 3
+
+// PullImagesOptions configures PullImages
+type PullImagesOptions struct {
+	// maximum number of images pulled at once, defaults to 3
+	Concurrency int
+	// credentials for the registries, credentials stored by `docker login` are used when nil
+	Auth *RegistryAuth
+	// returns the writer receiving the pull progress of an image, progress is discarded when nil
+	Progress func(image string) io.Writer
+}
+
+// PullImages pulls the images concurrently, overlapping network time when several distinct images are needed (e.g. the base images of a stack).
+// Every image is attempted, the images pulled successfully are returned in the order given, along with the joined failures.
+func (d *Docker) PullImages(images []string, opts PullImagesOptions) ([]string, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultPullConcurrency
+	}
+
+	encodedAuth, err := opts.Auth.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	pulled := make([]bool, len(images))
+
+	lock := sync.Mutex{}
+	pullErrors := []error{}
+
+	group := errgroup.Group{}
+	group.SetLimit(concurrency)
+
+	for i, image := range images {
+		i, image := i, image
+
+		group.Go(func() error {
+			logs := []io.Writer{}
+			if opts.Progress != nil {
+				logs = append(logs, opts.Progress(image))
+			}
+
+			if err := d.ImagePull(image, types.ImagePullOptions{RegistryAuth: encodedAuth}, logs...); err != nil {
+				lock.Lock()
+				pullErrors = append(pullErrors, fmt.Errorf("unable to pull %s: %w", image, err))
+				lock.Unlock()
+
+				return nil
+			}
+
+			pulled[i] = true
+
+			return nil
+		})
+	}
+
+	_ = group.Wait()
+
+	result := []string{}
+
+	for i, image := range images {
+		if pulled[i] {
+			result = append(result, image)
+		}
+	}
+
+	return result, errors.Join(pullErrors...)
+}
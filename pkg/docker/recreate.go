@@ -0,0 +1,200 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+)
+
+// PortMapping publishes a container port on the host
+type PortMapping struct {
+	// container port and optional protocol, e.g. "80" or "53/udp"
+	ContainerPort string
+	// host interface to bind, empty binds all interfaces
+	HostIP string
+	// host port, empty allocates a dynamic port
+	HostPort string
+}
+
+// RecreateWithPorts replaces a container with an identical one publishing only the provided ports, then starts it, returning the new container's ID.
+// Docker can't change the published ports of an existing container, so this is the only way to add or remove them.
+//
+// The config, environment, labels, mounts (including anonymous volumes) and network connections of the container are preserved.
+// The container is stopped first, if the replacement can't be created the original is restored and restarted.
+// Containers created with auto-remove are refused, as stopping them would delete them.
+func (d *Docker) RecreateWithPorts(nameOrID string, ports []PortMapping) (string, error) {
+	return d.recreate(nameOrID, true, func(config *container.Config, hostConfig *container.HostConfig) error {
+		if config.ExposedPorts == nil {
+			config.ExposedPorts = nat.PortSet{}
+		}
+
+		hostConfig.PortBindings = nat.PortMap{}
+
+		for _, mapping := range ports {
+			port, err := nat.NewPort(nat.SplitProtoPort(mapping.ContainerPort))
+			if err != nil {
+				return fmt.Errorf("invalid container port %s: %w", mapping.ContainerPort, err)
+			}
+
+			config.ExposedPorts[port] = struct{}{}
+			hostConfig.PortBindings[port] = append(hostConfig.PortBindings[port], nat.PortBinding{
+				HostIP:   mapping.HostIP,
+				HostPort: mapping.HostPort,
+			})
+		}
+
+		return nil
+	})
+}
+
+// AdoptContainer brings a container created outside of nitric under the management of a stack, returning the new container's ID.
+// Labels can't be changed after a container is created, so the container is replaced with an identical one labeled with the stack
+// (and the client's namespace), which is started if the original was running. Prefer setting labels at creation where possible,
+// e.g. with WithContainerLabels.
+//
+// The config, environment, mounts (including anonymous volumes) and network connections of the container are preserved.
+// Containers created with auto-remove are refused, as stopping them would delete them.
+func (d *Docker) AdoptContainer(nameOrID, stackName string) (string, error) {
+	return d.recreate(nameOrID, false, func(config *container.Config, hostConfig *container.HostConfig) error {
+		if config.Labels == nil {
+			config.Labels = map[string]string{}
+		}
+
+		config.Labels[StackLabel] = stackName
+
+		if d.namePrefix != "" {
+			config.Labels[NamespaceLabel] = d.namePrefix
+		}
+
+		return nil
+	})
+}
+
+// recreate replaces a container with one created from its configuration after applying modify, keeping its name.
+// The replacement is started if start is set or the original was running.
+func (d *Docker) recreate(nameOrID string, start bool, modify func(config *container.Config, hostConfig *container.HostConfig) error) (string, error) {
+	ctx := context.Background()
+
+	info, err := d.Client.ContainerInspect(ctx, nameOrID)
+	if err != nil {
+		return "", fmt.Errorf("unable to inspect container %s: %w", nameOrID, err)
+	}
+
+	// the original is stopped before it's replaced, which deletes an auto-removed container along with anything needed to restore it
+	if info.HostConfig != nil && info.HostConfig.AutoRemove {
+		return "", fmt.Errorf("container %s was created with auto-remove and would be deleted when stopped, it can't be recreated", nameOrID)
+	}
+
+	config := info.Config
+	hostConfig := info.HostConfig
+	name := strings.TrimPrefix(info.Name, "/")
+	wasRunning := info.State != nil && info.State.Running
+
+	if err := modify(config, hostConfig); err != nil {
+		return "", err
+	}
+
+	// anonymous volumes aren't part of the host config, mount them explicitly so the replacement keeps their data
+	for _, m := range info.Mounts {
+		if m.Type == mount.TypeVolume && !hasMountTarget(hostConfig, m.Destination) {
+			hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+				Type:     mount.TypeVolume,
+				Source:   m.Name,
+				Target:   m.Destination,
+				ReadOnly: !m.RW,
+			})
+		}
+	}
+
+	networkingConfig := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+
+	if info.NetworkSettings != nil {
+		for networkName, endpoint := range info.NetworkSettings.Networks {
+			networkingConfig.EndpointsConfig[networkName] = &network.EndpointSettings{
+				IPAMConfig: endpoint.IPAMConfig,
+				Links:      endpoint.Links,
+				Aliases:    endpoint.Aliases,
+			}
+		}
+	}
+
+	if err := d.Client.ContainerStop(ctx, info.ID, container.StopOptions{}); err != nil {
+		return "", fmt.Errorf("unable to stop container %s: %w", name, err)
+	}
+
+	// keep the original until its replacement exists, so it can be restored on failure
+	replacedName := name + "-replaced"
+
+	if err := d.Client.ContainerRename(ctx, info.ID, replacedName); err != nil {
+		return "", fmt.Errorf("unable to rename container %s: %w", name, err)
+	}
+
+	resp, err := d.Client.ContainerCreate(ctx, config, hostConfig, networkingConfig, nil, name)
+	if err != nil {
+		createErr := fmt.Errorf("unable to recreate container %s: %w", name, err)
+
+		if err := d.Client.ContainerRename(ctx, info.ID, name); err != nil {
+			return "", errors.Join(createErr, fmt.Errorf("unable to restore container %s: %w", name, err))
+		}
+
+		if wasRunning {
+			if err := d.Client.ContainerStart(ctx, info.ID, container.StartOptions{}); err != nil {
+				return "", errors.Join(createErr, fmt.Errorf("unable to restart container %s: %w", name, err))
+			}
+		}
+
+		return "", createErr
+	}
+
+	if err := d.Client.ContainerRemove(ctx, info.ID, container.RemoveOptions{}); err != nil {
+		return resp.ID, fmt.Errorf("unable to remove replaced container %s: %w", replacedName, err)
+	}
+
+	if start || wasRunning {
+		if err := d.Client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+			return resp.ID, fmt.Errorf("unable to start container %s: %w", name, err)
+		}
+	}
+
+	return resp.ID, nil
+}
+
+// hasMountTarget reports whether the host config already mounts something at the target path
+func hasMountTarget(hostConfig *container.HostConfig, target string) bool {
+	for _, m := range hostConfig.Mounts {
+		if m.Target == target {
+			return true
+		}
+	}
+
+	for _, bind := range hostConfig.Binds {
+		parts := strings.Split(bind, ":")
+		if len(parts) >= 2 && parts[1] == target {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,282 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+)
+
+// RegistryAuth holds the credentials used to authenticate with an image registry
+type RegistryAuth struct {
+	Username      string
+	Password      string
+	ServerAddress string
+	IdentityToken string
+}
+
+// encode returns the base64 encoded auth header value expected by the docker API.
+// A nil auth encodes to an empty string, which performs an anonymous request.
+func (a *RegistryAuth) encode() (string, error) {
+	if a == nil {
+		return "", nil
+	}
+
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      a.Username,
+		Password:      a.Password,
+		ServerAddress: a.ServerAddress,
+		IdentityToken: a.IdentityToken,
+	})
+}
+
+// ResolveDigest resolves a (possibly mutable) image reference to its immutable digest form, e.g. name@sha256:...
+// For multi-arch images the digest of the manifest list is returned.
+func (d *Docker) ResolveDigest(ref string, auth *RegistryAuth) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %s: %w", ref, err)
+	}
+
+	encodedAuth, err := auth.encode()
+	if err != nil {
+		return "", err
+	}
+
+	inspect, err := d.Client.DistributionInspect(context.Background(), reference.TagNameOnly(named).String(), encodedAuth)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve digest for %s: %w", ref, err)
+	}
+
+	canonical, err := reference.WithDigest(reference.TrimNamed(named), inspect.Descriptor.Digest)
+	if err != nil {
+		return "", err
+	}
+
+	return reference.FamiliarString(canonical), nil
+}
+
+// dockerHubAuthKey is the key docker login stores Docker Hub credentials under
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// dockerConfig is the subset of the docker CLI config file (~/.docker/config.json) describing registry credentials
+type dockerConfig struct {
+	Auths       map[string]dockerConfigAuth `json:"auths"`
+	CredsStore  string                      `json:"credsStore"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// credentialHelperResponse is the output of `docker-credential-<helper> get`
+type credentialHelperResponse struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+func dockerConfigPath() (string, error) {
+	if configDir := os.Getenv("DOCKER_CONFIG"); configDir != "" {
+		return filepath.Join(configDir, "config.json"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".docker", "config.json"), nil
+}
+
+// registryAuthKey normalizes a registry host to the key docker login stores its credentials under
+func registryAuthKey(registry string) string {
+	switch registry {
+	case "", "docker.io", "index.docker.io", "registry-1.docker.io":
+		return dockerHubAuthKey
+	default:
+		return registry
+	}
+}
+
+// fromCredentialHelper retrieves credentials for the registry from a docker credential helper, e.g. desktop or ecr-login
+func fromCredentialHelper(helper, serverAddress string) (*RegistryAuth, error) {
+	cmd := exec.Command(fmt.Sprintf("docker-credential-%s", helper), "get")
+	cmd.Stdin = strings.NewReader(serverAddress)
+
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+
+	if err := cmd.Run(); err != nil {
+		// helpers exit non-zero when they hold no credentials for the server
+		if strings.Contains(stdout.String(), "credentials not found") {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("credential helper %s failed for %s: %w", helper, serverAddress, err)
+	}
+
+	resp := &credentialHelperResponse{}
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return nil, fmt.Errorf("invalid response from credential helper %s: %w", helper, err)
+	}
+
+	// helpers return identity tokens with a placeholder username
+	if resp.Username == "<token>" {
+		return &RegistryAuth{ServerAddress: serverAddress, IdentityToken: resp.Secret}, nil
+	}
+
+	return &RegistryAuth{ServerAddress: serverAddress, Username: resp.Username, Password: resp.Secret}, nil
+}
+
+// ResolveRegistryAuth loads the credentials for a registry host (e.g. ghcr.io, or docker.io/empty for Docker Hub) stored by `docker login`,
+// consulting the configured credential helpers the same way the docker CLI does. Returns nil when no credentials are stored.
+func ResolveRegistryAuth(registry string) (*RegistryAuth, error) {
+	configPath, err := dockerConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read docker config %s: %w", configPath, err)
+	}
+
+	config := &dockerConfig{}
+	if err := json.Unmarshal(contents, config); err != nil {
+		return nil, fmt.Errorf("unable to parse docker config %s: %w", configPath, err)
+	}
+
+	serverAddress := registryAuthKey(registry)
+
+	// registry specific helpers take precedence over the default credential store
+	if helper, ok := config.CredHelpers[registry]; ok {
+		return fromCredentialHelper(helper, serverAddress)
+	}
+
+	if config.CredsStore != "" {
+		return fromCredentialHelper(config.CredsStore, serverAddress)
+	}
+
+	auth, ok := config.Auths[serverAddress]
+	if !ok {
+		return nil, nil
+	}
+
+	if auth.IdentityToken != "" {
+		return &RegistryAuth{ServerAddress: serverAddress, IdentityToken: auth.IdentityToken}, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials for %s in docker config: %w", serverAddress, err)
+	}
+
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid credentials for %s in docker config", serverAddress)
+	}
+
+	return &RegistryAuth{ServerAddress: serverAddress, Username: username, Password: password}, nil
+}
+
+// registryAuthForImage resolves the stored credentials for the registry hosting the image, returning the encoded auth header value
+func registryAuthForImage(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+
+	auth, err := ResolveRegistryAuth(reference.Domain(named))
+	if err != nil {
+		return "", err
+	}
+
+	return auth.encode()
+}
+
+// ErrRateLimited is returned when the registry refuses a request due to rate limiting (e.g. Docker Hub pull limits)
+var ErrRateLimited = errors.New("registry rate limit exceeded")
+
+// isRateLimited reports whether a registry error was caused by rate limiting. The daemon doesn't relay the registry's status code,
+// so the error is matched by the registry's TOOMANYREQUESTS error code or the text of the HTTP 429 status.
+func isRateLimited(err error) bool {
+	message := strings.ToLower(err.Error())
+
+	return strings.Contains(message, "toomanyrequests") || strings.Contains(message, strings.ToLower(http.StatusText(http.StatusTooManyRequests)))
+}
+
+// CheckBaseImageUpdate compares the digest of a locally pulled image with the registry's current digest for the same tag,
+// reporting whether a newer version is available. Returns ErrRateLimited when the registry can't currently be queried,
+// which callers should treat as "unknown" rather than a failure.
+func (d *Docker) CheckBaseImageUpdate(ref string, auth *RegistryAuth) (current, latestDigest string, updateAvailable bool, err error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", "", false, fmt.Errorf("invalid image reference %s: %w", ref, err)
+	}
+
+	inspect, _, err := d.Client.ImageInspectWithRaw(context.Background(), ref)
+	if errdefs.IsNotFound(err) {
+		return "", "", false, fmt.Errorf("image %s: %w", ref, ErrNotFound)
+	}
+
+	if err != nil {
+		return "", "", false, fmt.Errorf("unable to inspect image %s: %w", ref, err)
+	}
+
+	latest, err := d.ResolveDigest(ref, auth)
+	if err != nil {
+		if isRateLimited(err) {
+			return "", "", false, errors.Join(ErrRateLimited, err)
+		}
+
+		return "", "", false, err
+	}
+
+	// images built locally have no repo digests, so are always considered out of date
+	repoName := reference.TrimNamed(named).Name()
+
+	for _, repoDigest := range inspect.RepoDigests {
+		localNamed, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil || localNamed.Name() != repoName {
+			continue
+		}
+
+		current = reference.FamiliarString(localNamed)
+	}
+
+	return current, latest, current != latest, nil
+}
@@ -0,0 +1,93 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveRegistryAuth(t *testing.T) {
+	configDir := t.TempDir()
+
+	// auth is base64("user:pa:ss")
+	config := `{
+	"auths": {
+		"https://index.docker.io/v1/": {"auth": "dXNlcjpwYTpzcw=="},
+		"ghcr.io": {"identitytoken": "token"}
+	}
+}`
+
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("DOCKER_CONFIG", configDir)
+
+	tests := []struct {
+		registry string
+		want     *RegistryAuth
+	}{
+		{
+			registry: "docker.io",
+			want:     &RegistryAuth{ServerAddress: "https://index.docker.io/v1/", Username: "user", Password: "pa:ss"},
+		},
+		{
+			registry: "ghcr.io",
+			want:     &RegistryAuth{ServerAddress: "ghcr.io", IdentityToken: "token"},
+		},
+		{
+			registry: "registry.example.com",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.registry, func(t *testing.T) {
+			got, err := ResolveRegistryAuth(tt.registry)
+			if err != nil {
+				t.Fatalf("ResolveRegistryAuth() error = %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ResolveRegistryAuth() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	tests := []struct {
+		err  string
+		want bool
+	}{
+		{err: "toomanyrequests: You have reached your pull rate limit", want: true},
+		{err: "unexpected status code 429 Too Many Requests", want: true},
+		{err: "manifest for api:4290 not found"},
+		{err: "pull access denied for sha256:4291abc"},
+	}
+
+	for _, tt := range tests {
+		if got := isRateLimited(errors.New(tt.err)); got != tt.want {
+			t.Errorf("isRateLimited(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
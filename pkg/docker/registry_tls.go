@@ -0,0 +1,121 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrRegistryCertificate is returned when a registry's TLS certificate fails validation
+var ErrRegistryCertificate = errors.New("registry certificate failed validation")
+
+// RegistryTLS configures how TLS connections to a single registry are verified
+type RegistryTLS struct {
+	// path to a PEM bundle of CA certificates trusted for the registry, e.g. for a self-signed registry certificate
+	CAFile string
+	// skip verification of the registry's certificate
+	Insecure bool
+}
+
+// WithRegistryTLS - configures TLS verification for a single registry (host[:port]) used by builds, e.g. trusting the CA of a
+// self-hosted registry. Builds run on the nitric buildx builder, which pulls and pushes using this configuration.
+// Pulls made by the daemon itself use the daemon's configuration, the CA must be installed in /etc/docker/certs.d/<registry>/ca.crt
+// on the daemon host (or the registry listed in the daemon's insecure-registries).
+func WithRegistryTLS(registry string, tls RegistryTLS) ClientOption {
+	return func(o *clientOptions) {
+		if o.registryTLS == nil {
+			o.registryTLS = map[string]RegistryTLS{}
+		}
+
+		o.registryTLS[registry] = tls
+	}
+}
+
+// validateRegistryTLS ensures each configured CA bundle can be read, returning absolute paths as the builder reads them from
+// a different working directory
+func validateRegistryTLS(registries map[string]RegistryTLS) (map[string]RegistryTLS, error) {
+	validated := make(map[string]RegistryTLS, len(registries))
+
+	for registry, tls := range registries {
+		if tls.CAFile != "" {
+			caFile, err := filepath.Abs(tls.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CA file for registry %s: %w", registry, err)
+			}
+
+			if _, err := os.Stat(caFile); err != nil {
+				return nil, fmt.Errorf("unable to read CA file for registry %s: %w", registry, err)
+			}
+
+			tls.CAFile = caFile
+		}
+
+		validated[registry] = tls
+	}
+
+	return validated, nil
+}
+
+// buildkitdConfig renders the registry section of a buildkitd.toml for the registry TLS configuration
+func buildkitdConfig(registries map[string]RegistryTLS) string {
+	names := make([]string, 0, len(registries))
+	for name := range registries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	config := strings.Builder{}
+
+	for _, name := range names {
+		tls := registries[name]
+
+		fmt.Fprintf(&config, "[registry.%s]\n", strconv.Quote(name))
+
+		if tls.CAFile != "" {
+			fmt.Fprintf(&config, "  ca = [%s]\n", strconv.Quote(tls.CAFile))
+		}
+
+		if tls.Insecure {
+			config.WriteString("  insecure = true\n")
+		}
+	}
+
+	return config.String()
+}
+
+// isCertificateError reports whether a registry error was caused by a failure to verify its TLS certificate
+func isCertificateError(err error) bool {
+	return strings.Contains(err.Error(), "x509:") || strings.Contains(err.Error(), "certificate signed by unknown authority")
+}
+
+// certificateError explains how to trust a registry whose certificate failed validation during a daemon pull or push
+func certificateError(image string, err error) error {
+	registry := "docker.io"
+	if domain, _, found := strings.Cut(image, "/"); found && strings.ContainsAny(domain, ".:") {
+		registry = domain
+	}
+
+	return fmt.Errorf("%w for %s: install the registry's CA in /etc/docker/certs.d/%s/ca.crt on the docker host, or add it to the daemon's insecure-registries: %w",
+		ErrRegistryCertificate, registry, registry, err)
+}
@@ -0,0 +1,139 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// Resource is a daemon resource that can be exhausted by builds and containers
+type Resource string
+
+const (
+	ResourceMemory Resource = "memory"
+	ResourceDisk   Resource = "disk"
+)
+
+// resourceExhaustionMarkers are the messages reported by builds and containers that run out of memory or disk
+var resourceExhaustionMarkers = map[Resource][]string{
+	ResourceMemory: {"cannot allocate memory", "out of memory", "signal: killed", "exit code: 137"},
+	ResourceDisk:   {"no space left on device", "disk quota exceeded"},
+}
+
+// exhaustedResource returns the resource a line of output reports as exhausted, if any
+func exhaustedResource(line []byte) (Resource, bool) {
+	lower := strings.ToLower(string(line))
+
+	for _, resource := range []Resource{ResourceMemory, ResourceDisk} {
+		for _, marker := range resourceExhaustionMarkers[resource] {
+			if strings.Contains(lower, marker) {
+				return resource, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// resourceWriter records the first resource reported as exhausted in build output
+type resourceWriter struct {
+	lineWriter
+	resource Resource
+}
+
+func newResourceWriter() *resourceWriter {
+	w := &resourceWriter{}
+	w.onLine = w.line
+
+	return w
+}
+
+// line is called with the lineWriter lock held
+func (w *resourceWriter) line(line []byte) {
+	if w.resource != "" {
+		return
+	}
+
+	if resource, ok := exhaustedResource(line); ok {
+		w.resource = resource
+	}
+}
+
+// exhausted returns the resource reported as exhausted, or an empty string if there wasn't one
+func (w *resourceWriter) exhausted() Resource {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.resource
+}
+
+// isDockerDesktop reports whether the daemon runs in a Docker Desktop VM, where its resources are capped by the VM settings
+func isDockerDesktop(info types.Info) bool {
+	return strings.Contains(info.OperatingSystem, "Docker Desktop")
+}
+
+// ResourceLimitHint returns advice on raising the limit of an exhausted resource when the daemon runs in Docker Desktop,
+// or an empty string when the daemon isn't limited by Docker Desktop or its limits can't be determined
+func (d *Docker) ResourceLimitHint(resource Resource) string {
+	info, err := d.Client.Info(context.Background())
+	if err != nil || !isDockerDesktop(info) {
+		return ""
+	}
+
+	switch resource {
+	case ResourceMemory:
+		if info.MemTotal <= 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("Docker Desktop has only %d MB of memory allocated; increase it in Settings → Resources.", info.MemTotal/1024/1024)
+	case ResourceDisk:
+		usage, err := d.Client.DiskUsage(context.Background(), types.DiskUsageOptions{})
+		if err != nil {
+			return "Docker Desktop has run out of disk space; increase the disk limit in Settings → Resources."
+		}
+
+		used := usage.LayersSize
+		for _, volume := range usage.Volumes {
+			if volume.UsageData != nil && volume.UsageData.Size > 0 {
+				used += volume.UsageData.Size
+			}
+		}
+
+		return fmt.Sprintf("Docker Desktop has run out of disk space with %d MB in use; increase the disk limit in Settings → Resources.", used/1024/1024)
+	default:
+		return ""
+	}
+}
+
+// withResourceHint adds advice on raising Docker Desktop's limits to an error caused by an exhausted resource
+func (d *Docker) withResourceHint(err error, resource Resource) error {
+	if resource == "" {
+		return err
+	}
+
+	hint := d.ResourceLimitHint(resource)
+	if hint == "" {
+		return err
+	}
+
+	return fmt.Errorf("%w\n%s", err, hint)
+}
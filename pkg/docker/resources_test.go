@@ -0,0 +1,102 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestResourceWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   Resource
+	}{
+		{
+			name:   "out of memory",
+			output: "#8 [3/3] RUN npm run build\n#8 ERROR: process \"/bin/sh -c npm run build\" did not complete successfully: exit code: 137\n",
+			want:   ResourceMemory,
+		},
+		{
+			name:   "out of disk",
+			output: "#6 ERROR: failed to copy files: write /var/lib/docker/tmp/layer: no space left on device\n",
+			want:   ResourceDisk,
+		},
+		{
+			name:   "other failure",
+			output: "#5 ERROR: process \"/bin/sh -c exit 1\" did not complete successfully: exit code: 1\n",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newResourceWriter()
+
+			if _, err := w.Write([]byte(tt.output)); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := w.exhausted(); got != tt.want {
+				t.Errorf("exhausted() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithResourceHint(t *testing.T) {
+	operatingSystem := "Docker Desktop"
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/info") {
+			_ = json.NewEncoder(w).Encode(types.Info{OperatingSystem: operatingSystem, MemTotal: 2 * 1024 * 1024 * 1024})
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+	buildErr := errors.New("exit status 1")
+
+	err := d.withResourceHint(buildErr, ResourceMemory)
+	if !errors.Is(err, buildErr) {
+		t.Errorf("expected the build error to be wrapped, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "Docker Desktop has only 2048 MB of memory allocated") {
+		t.Errorf("expected a memory hint, got %v", err)
+	}
+
+	if err := d.withResourceHint(buildErr, ""); err != buildErr {
+		t.Errorf("expected the error to be unchanged when no resource was exhausted, got %v", err)
+	}
+
+	operatingSystem = "Ubuntu 22.04.4 LTS"
+
+	if err := d.withResourceHint(buildErr, ResourceMemory); err != buildErr {
+		t.Errorf("expected the error to be unchanged outside of Docker Desktop, got %v", err)
+	}
+}
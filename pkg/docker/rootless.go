@@ -0,0 +1,80 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ErrRootlessNetworking is returned when a network or port configuration isn't supported by a rootless engine
+var ErrRootlessNetworking = errors.New("unsupported by rootless engine networking")
+
+// unprivilegedPortStart is the lowest port an unprivileged process can bind by default (net.ipv4.ip_unprivileged_port_start)
+const unprivilegedPortStart = 1024
+
+// privilegedHostPorts returns the published host ports below unprivilegedPortStart, which rootless engines can't bind by default
+func privilegedHostPorts(hostConfig *container.HostConfig) []string {
+	ports := []string{}
+
+	if hostConfig == nil {
+		return ports
+	}
+
+	for containerPort, bindings := range hostConfig.PortBindings {
+		for _, binding := range bindings {
+			if hostPort, err := strconv.Atoi(binding.HostPort); err == nil && hostPort > 0 && hostPort < unprivilegedPortStart {
+				ports = append(ports, fmt.Sprintf("%d->%s", hostPort, containerPort))
+			}
+		}
+	}
+
+	sort.Strings(ports)
+
+	return ports
+}
+
+// checkRootlessPorts explains why privileged host ports can't be published by a rootless engine, nil when there are none
+func checkRootlessPorts(info *EngineInfo, hostConfig *container.HostConfig) error {
+	ports := privilegedHostPorts(hostConfig)
+	if len(ports) == 0 || !info.Capabilities.Rootless {
+		return nil
+	}
+
+	return fmt.Errorf("publishing host ports %s: %w, ports below %d require lowering net.ipv4.ip_unprivileged_port_start on the host "+
+		"(e.g. `sysctl net.ipv4.ip_unprivileged_port_start=80`) or publishing a higher host port", strings.Join(ports, ", "), ErrRootlessNetworking, unprivilegedPortStart)
+}
+
+// checkRootlessNetworkDriver explains why a network driver can't be used by a rootless engine, nil when it can be
+func checkRootlessNetworkDriver(info *EngineInfo, driver string) error {
+	if !info.Capabilities.Rootless || driver == "" || driver == "bridge" {
+		return nil
+	}
+
+	userModeNetworking := "slirp4netns or pasta"
+	if info.Type == EngineType_Docker {
+		userModeNetworking = "RootlessKit"
+	}
+
+	return fmt.Errorf("network driver %s: %w, rootless %s engines only support bridge networks, which are connected to the host through %s. "+
+		"Container IPs aren't reachable from the host, use published ports instead", driver, ErrRootlessNetworking, info.Type, userModeNetworking)
+}
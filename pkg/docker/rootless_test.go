@@ -0,0 +1,68 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestCheckRootlessPorts(t *testing.T) {
+	hostConfig := &container.HostConfig{
+		PortBindings: nat.PortMap{
+			"80/tcp":   []nat.PortBinding{{HostPort: "80"}},
+			"8080/tcp": []nat.PortBinding{{HostPort: "8080"}},
+			"9000/tcp": []nat.PortBinding{{HostPort: ""}},
+		},
+	}
+
+	rootless := &EngineInfo{Type: EngineType_Podman, Capabilities: EngineCapabilities{Rootless: true}}
+	rootful := &EngineInfo{Type: EngineType_Podman}
+
+	if err := checkRootlessPorts(rootless, hostConfig); !errors.Is(err, ErrRootlessNetworking) {
+		t.Errorf("expected ErrRootlessNetworking publishing port 80 on a rootless engine, got %v", err)
+	}
+
+	if err := checkRootlessPorts(rootful, hostConfig); err != nil {
+		t.Errorf("expected no error on a rootful engine, got %v", err)
+	}
+
+	delete(hostConfig.PortBindings, "80/tcp")
+
+	if err := checkRootlessPorts(rootless, hostConfig); err != nil {
+		t.Errorf("expected no error for unprivileged ports, got %v", err)
+	}
+}
+
+func TestCheckRootlessNetworkDriver(t *testing.T) {
+	rootless := &EngineInfo{Type: EngineType_Podman, Capabilities: EngineCapabilities{Rootless: true}}
+
+	if err := checkRootlessNetworkDriver(rootless, "bridge"); err != nil {
+		t.Errorf("expected bridge networks to be supported, got %v", err)
+	}
+
+	if err := checkRootlessNetworkDriver(rootless, "macvlan"); !errors.Is(err, ErrRootlessNetworking) {
+		t.Errorf("expected ErrRootlessNetworking for a macvlan network, got %v", err)
+	}
+
+	if err := checkRootlessNetworkDriver(&EngineInfo{Type: EngineType_Podman}, "macvlan"); err != nil {
+		t.Errorf("expected no error on a rootful engine, got %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ContainerResult is the outcome of running a container to completion
+type ContainerResult struct {
+	ID       string
+	Name     string
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+	// the failure to create, start or wait for the container, nil if it ran to completion (whatever its exit code)
+	Err error
+}
+
+// RunAndWaitMany creates and starts a container for each spec, waits for them all to exit and collects their exit codes and logs.
+// At most GOMAXPROCS containers run at once. Results are returned in the order of the specs.
+//
+// Every created container is removed before returning, including when the context is cancelled, which stops the running containers.
+// The returned error joins the failures of each container, a non-zero exit code isn't considered a failure.
+func (d *Docker) RunAndWaitMany(ctx context.Context, specs []*ContainerSpec) ([]ContainerResult, error) {
+	results := make([]ContainerResult, len(specs))
+
+	maxConcurrent := make(chan struct{}, min(runtime.NumCPU(), runtime.GOMAXPROCS(0)))
+	waitGroup := sync.WaitGroup{}
+
+	for i, spec := range specs {
+		waitGroup.Add(1)
+
+		go func(idx int, spec *ContainerSpec) {
+			defer waitGroup.Done()
+
+			select {
+			case maxConcurrent <- struct{}{}:
+			case <-ctx.Done():
+				results[idx] = ContainerResult{Name: spec.Name, Err: ctx.Err()}
+				return
+			}
+
+			defer func() {
+				<-maxConcurrent
+			}()
+
+			results[idx] = d.runAndWait(ctx, spec)
+		}(i, spec)
+	}
+
+	waitGroup.Wait()
+
+	errs := []error{}
+
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// runAndWait runs a single container to completion, always removing it afterwards
+func (d *Docker) runAndWait(ctx context.Context, spec *ContainerSpec) (result ContainerResult) {
+	result.Name = spec.Name
+
+	id, err := d.CreateFromSpec(spec)
+	if err != nil {
+		result.Err = fmt.Errorf("unable to create container %s: %w", spec.Name, err)
+		return result
+	}
+
+	result.ID = id
+
+	// removal uses a fresh context so cancelled runs are still cleaned up, deferred to also run if a panic unwinds the goroutine
+	defer func() {
+		err := d.Client.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
+		if err != nil && result.Err == nil {
+			result.Err = fmt.Errorf("unable to remove container %s: %w", id, err)
+		}
+	}()
+
+	waitC, errC := d.Client.ContainerWait(ctx, id, container.WaitConditionNextExit)
+
+	if err := d.Client.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		result.Err = fmt.Errorf("unable to start container %s: %w", id, err)
+		return result
+	}
+
+	select {
+	case status := <-waitC:
+		result.ExitCode = int(status.StatusCode)
+	case err := <-errC:
+		result.Err = fmt.Errorf("unable to wait for container %s: %w", id, err)
+		return result
+	}
+
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+
+	if err := d.Logs(ctx, id, LogOptions{}, &stdout, &stderr); err != nil {
+		result.Err = err
+	}
+
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
+
+	return result
+}
+
+// cleanupStopTimeout is the time containers are given to exit gracefully during cleanup before they're killed
+const cleanupStopTimeout = 10 * time.Second
+
+// RunWithCleanup creates and starts a container for each spec, tying their lifetime to the context and the process:
+// when the context is cancelled or the process receives SIGINT or SIGTERM, the started containers are stopped and removed.
+//
+// The returned channel receives the result of the cleanup and is then closed, callers handling the interrupt themselves should
+// wait on it before exiting. Containers can't be cleaned up if the process is killed (SIGKILL).
+// If any container fails to start, the containers already started are cleaned up before the error is returned.
+func (d *Docker) RunWithCleanup(ctx context.Context, specs []*ContainerSpec) ([]string, <-chan error, error) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+
+	ids := []string{}
+
+	for _, spec := range specs {
+		id, err := d.CreateFromSpec(spec)
+		if err == nil {
+			ids = append(ids, id)
+			err = d.Client.ContainerStart(ctx, id, container.StartOptions{})
+		}
+
+		if err != nil {
+			stop()
+
+			return nil, nil, errors.Join(fmt.Errorf("unable to start container %s: %w", spec.Name, err), d.cleanup(ids))
+		}
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		defer close(done)
+		defer stop()
+
+		<-ctx.Done()
+
+		done <- d.cleanup(ids)
+	}()
+
+	return ids, done, nil
+}
+
+// cleanup stops and removes containers, continuing past failures so every container is attempted
+func (d *Docker) cleanup(ids []string) error {
+	// a fresh context, as cleanup typically runs once the caller's context is cancelled
+	ctx := context.Background()
+	timeout := int(cleanupStopTimeout.Seconds())
+
+	errs := []error{}
+
+	for _, id := range ids {
+		if err := d.Client.ContainerStop(ctx, id, container.StopOptions{Timeout: &timeout}); err != nil {
+			errs = append(errs, fmt.Errorf("unable to stop container %s: %w", id, err))
+		}
+
+		if err := d.Client.ContainerRemove(ctx, id, container.RemoveOptions{Force: true}); err != nil {
+			errs = append(errs, fmt.Errorf("unable to remove container %s: %w", id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// defaultSmokeTestGracePeriod is how long a smoke tested container must keep running
+const defaultSmokeTestGracePeriod = 5 * time.Second
+
+// SmokeOptions configures SmokeTest
+type SmokeOptions struct {
+	// overrides the image's command, e.g. to run a health or version check
+	Cmd []string
+	// how long the container must run without crashing, defaults to 5 seconds
+	GracePeriod time.Duration
+	// environment variables in KEY=value form, e.g. configuration required for the entrypoint to start
+	Env []string
+}
+
+// SmokeTest runs an image to check its entrypoint starts, catching broken images right after they're built.
+// The test passes if the container is still running after the grace period, or has already exited with status 0 (e.g. a one-shot command).
+// On failure the error includes the container's exit reason and recent logs. The container is always removed.
+func (d *Docker) SmokeTest(ctx context.Context, idOrTag string, opts SmokeOptions) error {
+	gracePeriod := opts.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = defaultSmokeTestGracePeriod
+	}
+
+	resp, err := d.Client.ContainerCreate(ctx, &container.Config{
+		Image: idOrTag,
+		Cmd:   opts.Cmd,
+		Env:   opts.Env,
+	}, &container.HostConfig{}, nil, nil, "")
+	if err != nil {
+		return fmt.Errorf("unable to create smoke test container for %s: %w", idOrTag, err)
+	}
+
+	defer func() {
+		_ = d.Client.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+	}()
+
+	waitC, errC := d.Client.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
+
+	if err := d.Client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("unable to start smoke test container for %s: %w", idOrTag, err)
+	}
+
+	select {
+	case <-time.After(gracePeriod):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errC:
+		return fmt.Errorf("unable to wait for smoke test container for %s: %w", idOrTag, err)
+	case <-waitC:
+	}
+
+	status, err := d.Status(resp.ID)
+	if err != nil {
+		return err
+	}
+
+	if status.ExitCode == 0 && status.Error == "" && !status.OOMKilled {
+		return nil
+	}
+
+	logs := bytes.Buffer{}
+	_ = d.Logs(context.Background(), resp.ID, LogOptions{Tail: "50"}, &logs, &logs)
+
+	return fmt.Errorf("smoke test of %s failed, %s:\n%s", idOrTag, status.ExitReason(), logs.String())
+}
+
+// OneShotOptions configures the container of RunOneShot
+type OneShotOptions struct {
+	// environment variables in KEY=value form
+	Env        []string
+	WorkingDir string
+	User       string
+	Mounts     []mount.Mount
+	// network to connect the container to, e.g. to reach a database being migrated
+	NetworkName string
+}
+
+// RunOneShot runs a command in a new container from the image, e.g. a migration or seed task, returning its exit code and combined output.
+// The container is removed once the command exits. A non-zero exit code isn't an error, err is only set when the command couldn't be run.
+// Cancelling the context stops the command.
+func (d *Docker) RunOneShot(ctx context.Context, image string, cmd []string, opts OneShotOptions) (int, string, error) {
+	hostConfig := &container.HostConfig{
+		AutoRemove: true,
+		Mounts:     opts.Mounts,
+	}
+
+	if opts.NetworkName != "" {
+		hostConfig.NetworkMode = container.NetworkMode(opts.NetworkName)
+	}
+
+	resp, err := d.Client.ContainerCreate(ctx, &container.Config{
+		Image:      image,
+		Cmd:        cmd,
+		Env:        opts.Env,
+		WorkingDir: opts.WorkingDir,
+		User:       opts.User,
+	}, hostConfig, nil, nil, "")
+	if err != nil {
+		return 0, "", fmt.Errorf("unable to create container for %s: %w", image, err)
+	}
+
+	// in case the container never starts, or the context is cancelled, as only exited containers are removed automatically
+	defer func() {
+		_ = d.Client.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+	}()
+
+	// attach before starting, so no output is missed
+	attach, err := d.Client.ContainerAttach(ctx, resp.ID, container.AttachOptions{Stream: true, Stdout: true, Stderr: true})
+	if err != nil {
+		return 0, "", fmt.Errorf("unable to attach to container %s: %w", resp.ID, err)
+	}
+
+	defer attach.Close()
+
+	output := bytes.Buffer{}
+	outputDone := make(chan struct{})
+
+	go func() {
+		defer close(outputDone)
+
+		_, _ = stdcopy.StdCopy(&output, &output, attach.Reader)
+	}()
+
+	// wait for removal, the exit code is still reported for auto removed containers
+	waitC, errC := d.Client.ContainerWait(ctx, resp.ID, container.WaitConditionRemoved)
+
+	if err := d.Client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return 0, "", fmt.Errorf("unable to start container %s: %w", resp.ID, err)
+	}
+
+	select {
+	case status := <-waitC:
+		<-outputDone
+
+		if status.Error != nil {
+			return 0, output.String(), fmt.Errorf("unable to run %s: %s", image, status.Error.Message)
+		}
+
+		return int(status.StatusCode), output.String(), nil
+	case err := <-errC:
+		// stop reading output before returning what was captured
+		attach.Close()
+		<-outputDone
+
+		return 0, output.String(), fmt.Errorf("unable to wait for container %s: %w", resp.ID, err)
+	}
+}
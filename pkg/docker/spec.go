@@ -0,0 +1,265 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/go-connections/nat"
+	"github.com/samber/lo"
+)
+
+// ContainerSpec is the full configuration needed to create a container
+type ContainerSpec struct {
+	Name             string
+	Config           *container.Config
+	HostConfig       *container.HostConfig
+	NetworkingConfig *network.NetworkingConfig
+}
+
+type SpecOption func(*ContainerSpec) error
+
+// WithContainerName - sets the name of the container
+func WithContainerName(name string) SpecOption {
+	return func(s *ContainerSpec) error {
+		s.Name = name
+		return nil
+	}
+}
+
+// WithContainerEnv - adds environment variables to the container
+func WithContainerEnv(env map[string]string) SpecOption {
+	return func(s *ContainerSpec) error {
+		for k, v := range env {
+			if k == "" || strings.Contains(k, "=") {
+				return fmt.Errorf("invalid environment variable name %q", k)
+			}
+
+			s.Config.Env = append(s.Config.Env, fmt.Sprintf("%s=%s", k, v))
+		}
+
+		return nil
+	}
+}
+
+// WithContainerPort - publishes a container port on the host, using docker's port syntax, e.g. "8080:80", "127.0.0.1:8080:80/tcp" or "80" for a dynamic host port
+func WithContainerPort(port string) SpecOption {
+	return func(s *ContainerSpec) error {
+		mappings, err := nat.ParsePortSpec(port)
+		if err != nil {
+			return fmt.Errorf("invalid port %q: %w", port, err)
+		}
+
+		if s.Config.ExposedPorts == nil {
+			s.Config.ExposedPorts = nat.PortSet{}
+		}
+
+		if s.HostConfig.PortBindings == nil {
+			s.HostConfig.PortBindings = nat.PortMap{}
+		}
+
+		for _, mapping := range mappings {
+			s.Config.ExposedPorts[mapping.Port] = struct{}{}
+			s.HostConfig.PortBindings[mapping.Port] = append(s.HostConfig.PortBindings[mapping.Port], mapping.Binding)
+		}
+
+		return nil
+	}
+}
+
+// WithContainerMount - adds a mount (e.g. a bind mount or named volume) to the container
+func WithContainerMount(m mount.Mount) SpecOption {
+	return func(s *ContainerSpec) error {
+		if m.Target == "" {
+			return errors.New("invalid mount: a target is required")
+		}
+
+		if m.Type == mount.TypeBind && m.Source == "" {
+			return fmt.Errorf("invalid bind mount to %s: a source is required", m.Target)
+		}
+
+		s.HostConfig.Mounts = append(s.HostConfig.Mounts, m)
+
+		return nil
+	}
+}
+
+// VolumeMountOptions configures how a named volume is mounted, the zero value uses the daemon's default (local) driver
+type VolumeMountOptions struct {
+	// Driver creates the volume with a specific volume driver when it doesn't already exist
+	Driver string
+	// DriverOptions are passed to the driver when creating the volume, to the local driver if Driver is empty
+	DriverOptions map[string]string
+	// NoCopy disables populating a new volume with the image's content at the target path
+	NoCopy   bool
+	ReadOnly bool
+}
+
+// WithContainerVolume - mounts a named volume into the container, creating it with the provided driver configuration if missing.
+// Mounting a subpath of a volume requires a newer docker API than this client supports, mount the whole volume instead.
+func WithContainerVolume(volumeName, target string, opts VolumeMountOptions) SpecOption {
+	m := mount.Mount{
+		Type:     mount.TypeVolume,
+		Source:   volumeName,
+		Target:   target,
+		ReadOnly: opts.ReadOnly,
+	}
+
+	if opts.NoCopy || opts.Driver != "" || len(opts.DriverOptions) > 0 {
+		m.VolumeOptions = &mount.VolumeOptions{NoCopy: opts.NoCopy}
+
+		// options without a driver name are passed to the default "local" driver
+		if opts.Driver != "" || len(opts.DriverOptions) > 0 {
+			m.VolumeOptions.DriverConfig = &mount.Driver{
+				Name:    opts.Driver,
+				Options: opts.DriverOptions,
+			}
+		}
+	}
+
+	return WithContainerMount(m)
+}
+
+// WithContainerLabels - adds labels to the container
+func WithContainerLabels(labels map[string]string) SpecOption {
+	return func(s *ContainerSpec) error {
+		if s.Config.Labels == nil {
+			s.Config.Labels = map[string]string{}
+		}
+
+		for k, v := range labels {
+			s.Config.Labels[k] = v
+		}
+
+		return nil
+	}
+}
+
+// WithContainerNetwork - connects the container to a network, reachable by peers through the provided aliases
+func WithContainerNetwork(networkName string, aliases ...string) SpecOption {
+	return func(s *ContainerSpec) error {
+		if networkName == "" {
+			return errors.New("invalid network: a name is required")
+		}
+
+		if s.NetworkingConfig == nil {
+			s.NetworkingConfig = &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+		}
+
+		s.NetworkingConfig.EndpointsConfig[networkName] = &network.EndpointSettings{Aliases: aliases}
+
+		// the endpoint config of the network mode's network is applied at creation, without it the container joins the default bridge
+		if s.HostConfig.NetworkMode == "" {
+			s.HostConfig.NetworkMode = container.NetworkMode(networkName)
+		}
+
+		return nil
+	}
+}
+
+// NewContainerSpec creates the configuration for a container running the image, validating the provided options
+func NewContainerSpec(image string, options ...SpecOption) (*ContainerSpec, error) {
+	if image == "" {
+		return nil, errors.New("an image is required")
+	}
+
+	spec := &ContainerSpec{
+		Config:     &container.Config{Image: image},
+		HostConfig: &container.HostConfig{},
+	}
+
+	for _, o := range options {
+		if err := o(spec); err != nil {
+			return nil, err
+		}
+	}
+
+	return spec, nil
+}
+
+// CreateFromSpec creates a container from the spec, returning its ID.
+// Engines older than API 1.44 only connect a container to a single network at creation, so any further networks in the spec
+// are connected, with their aliases, once the container is created.
+func (d *Docker) CreateFromSpec(spec *ContainerSpec) (string, error) {
+	if spec.NetworkingConfig == nil || len(spec.NetworkingConfig.EndpointsConfig) <= 1 || versions.GreaterThanOrEqualTo(d.Client.ClientVersion(), "1.44") {
+		return d.ContainerCreate(spec.Config, spec.HostConfig, spec.NetworkingConfig, spec.Name)
+	}
+
+	networkNames := lo.Keys(spec.NetworkingConfig.EndpointsConfig)
+	sort.SliceStable(networkNames, func(i, j int) bool {
+		// the network mode's network must be connected at creation
+		if networkNames[i] == string(spec.HostConfig.NetworkMode) || networkNames[j] == string(spec.HostConfig.NetworkMode) {
+			return networkNames[i] == string(spec.HostConfig.NetworkMode)
+		}
+
+		return networkNames[i] < networkNames[j]
+	})
+
+	networkingConfig := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			networkNames[0]: spec.NetworkingConfig.EndpointsConfig[networkNames[0]],
+		},
+	}
+
+	id, err := d.ContainerCreate(spec.Config, spec.HostConfig, networkingConfig, spec.Name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, networkName := range networkNames[1:] {
+		if err := d.NetworkConnect(networkName, id, spec.NetworkingConfig.EndpointsConfig[networkName].Aliases...); err != nil {
+			// remove the partially connected container, callers only clean up containers that were created successfully
+			if removeErr := d.Client.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true}); removeErr != nil {
+				return "", errors.Join(err, fmt.Errorf("unable to remove container %s: %w", id, removeErr))
+			}
+
+			return "", err
+		}
+	}
+
+	return id, nil
+}
+
+// EnsureContainer creates a container from the spec, first removing any existing container with the same name.
+// Use this when the container's configuration may have changed and should always reflect the spec, CreateFromSpec fails on a name conflict instead.
+func (d *Docker) EnsureContainer(spec *ContainerSpec) (string, error) {
+	if spec.Name != "" {
+		name := d.containerName(spec.Name)
+
+		exists, err := d.ContainerExists(name)
+		if err != nil {
+			return "", err
+		}
+
+		if exists {
+			err := d.Client.ContainerRemove(context.Background(), name, container.RemoveOptions{Force: true})
+			if err != nil {
+				return "", fmt.Errorf("unable to remove existing container %s: %w", name, err)
+			}
+		}
+	}
+
+	return d.CreateFromSpec(spec)
+}
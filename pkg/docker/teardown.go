@@ -0,0 +1,74 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// TeardownStack removes the containers (running or not) and networks of a stack, and its volumes when removeVolumes is set.
+// Resources are found by their StackLabel, limited to the client's namespace, so resources created outside of nitric are never touched.
+// Failures are collected and returned together, after every resource has been attempted.
+func (d *Docker) TeardownStack(stackName string, removeVolumes bool) error {
+	ctx := context.Background()
+	labels := map[string]string{StackLabel: stackName}
+	teardownErrors := []error{}
+
+	containers, err := d.listByLabel(labels)
+	if err != nil {
+		return fmt.Errorf("unable to list containers of stack %s: %w", stackName, err)
+	}
+
+	for _, con := range containers {
+		if err := d.Client.ContainerRemove(ctx, con.ID, container.RemoveOptions{Force: true}); err != nil {
+			teardownErrors = append(teardownErrors, fmt.Errorf("unable to remove container %s: %w", con.ID, err))
+		}
+	}
+
+	// networks can only be removed once the containers connected to them are gone
+	networks, err := d.NetworksByLabel(labels)
+	if err != nil {
+		return errors.Join(append(teardownErrors, err)...)
+	}
+
+	for _, nw := range networks {
+		if err := d.Client.NetworkRemove(ctx, nw.ID); err != nil {
+			teardownErrors = append(teardownErrors, fmt.Errorf("unable to remove network %s: %w", nw.Name, err))
+		}
+	}
+
+	if !removeVolumes {
+		return errors.Join(teardownErrors...)
+	}
+
+	volumes, err := d.VolumesByLabel(labels)
+	if err != nil {
+		return errors.Join(append(teardownErrors, err)...)
+	}
+
+	for _, vol := range volumes {
+		if err := d.Client.VolumeRemove(ctx, vol.Name, false); err != nil {
+			teardownErrors = append(teardownErrors, fmt.Errorf("unable to remove volume %s: %w", vol.Name, err))
+		}
+	}
+
+	return errors.Join(teardownErrors...)
+}
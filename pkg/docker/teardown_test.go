@@ -0,0 +1,175 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newLabeledResourcesDaemon fakes a docker daemon that records created networks and volumes,
+// listing and removing them by their labels
+func newLabeledResourcesDaemon(t *testing.T) *httptest.Server {
+	var lock sync.Mutex
+
+	networks := map[string]map[string]string{}
+	volumes := map[string]map[string]string{}
+
+	matching := func(r *http.Request, resources map[string]map[string]string) []string {
+		args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+		if err != nil {
+			t.Errorf("invalid filters: %v", err)
+		}
+
+		names := []string{}
+
+		for name, labels := range resources {
+			if args.MatchKVList("label", labels) {
+				names = append(names, name)
+			}
+		}
+
+		return names
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/networks/") && !strings.HasSuffix(r.URL.Path, "/networks/"):
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "network not found"})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/networks/create"):
+			req := types.NetworkCreateRequest{}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			networks[req.Name] = req.Labels
+
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(types.NetworkCreateResponse{ID: req.Name})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/networks"):
+			resources := []types.NetworkResource{}
+			for _, name := range matching(r, networks) {
+				resources = append(resources, types.NetworkResource{ID: name, Name: name, Labels: networks[name]})
+			}
+
+			_ = json.NewEncoder(w).Encode(resources)
+		case r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "/networks/"):
+			delete(networks, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/volumes/create"):
+			req := volume.CreateOptions{}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			volumes[req.Name] = req.Labels
+
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(volume.Volume{Name: req.Name, Labels: req.Labels})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/volumes"):
+			resp := volume.ListResponse{}
+			for _, name := range matching(r, volumes) {
+				resp.Volumes = append(resp.Volumes, &volume.Volume{Name: name, Labels: volumes[name]})
+			}
+
+			_ = json.NewEncoder(w).Encode(resp)
+		case r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "/volumes/"):
+			delete(volumes, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/json"):
+			_ = json.NewEncoder(w).Encode([]types.Container{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestCreatedNetworksAndVolumesAreFoundByLabel(t *testing.T) {
+	daemon := newLabeledResourcesDaemon(t)
+	defer daemon.Close()
+
+	d := newTestDocker(t, daemon)
+	d.namePrefix = "ci-1"
+	d.sessionID = "session-1"
+
+	other := newTestDocker(t, daemon)
+	other.namePrefix = "ci-2"
+
+	stackLabels := map[string]string{StackLabel: "test"}
+
+	if _, err := d.NetworkCreateWithOptions("test-net", NetworkOptions{Labels: stackLabels}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.VolumeCreate(context.Background(), volume.CreateOptions{Name: "test-data", Labels: stackLabels}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same stack created by a client in another namespace
+	if _, err := other.NetworkCreateWithOptions("other-net", NetworkOptions{Labels: stackLabels}); err != nil {
+		t.Fatal(err)
+	}
+
+	networks, err := d.NetworksByLabel(stackLabels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(networks) != 1 || networks[0].Name != "test-net" {
+		t.Fatalf("expected only test-net to be found, got %v", networks)
+	}
+
+	wantLabels := map[string]string{StackLabel: "test", NamespaceLabel: "ci-1", SessionLabel: "session-1"}
+	if diff := cmp.Diff(wantLabels, networks[0].Labels); diff != "" {
+		t.Errorf("unexpected network labels (-want +got):\n%s", diff)
+	}
+
+	volumes, err := d.VolumesByLabel(map[string]string{SessionLabel: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(volumes) != 1 || volumes[0].Name != "test-data" {
+		t.Fatalf("expected test-data to be found by session, got %v", volumes)
+	}
+
+	if err := d.TeardownStack("test", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if networks, _ := d.NetworksByLabel(stackLabels); len(networks) != 0 {
+		t.Errorf("expected the stack's networks to be removed, got %v", networks)
+	}
+
+	if volumes, _ := d.VolumesByLabel(stackLabels); len(volumes) != 0 {
+		t.Errorf("expected the stack's volumes to be removed, got %v", volumes)
+	}
+
+	if networks, _ := other.NetworksByLabel(stackLabels); len(networks) != 1 {
+		t.Errorf("expected the network of the other namespace to be kept, got %v", networks)
+	}
+}
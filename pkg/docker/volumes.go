@@ -0,0 +1,46 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/volume"
+)
+
+// VolumeCreate creates a volume, labeled with the client's namespace and session in addition to the provided labels
+func (d *Docker) VolumeCreate(ctx context.Context, options volume.CreateOptions) (volume.Volume, error) {
+	options.Labels = d.clientLabels(options.Labels)
+
+	vol, err := d.Client.VolumeCreate(ctx, options)
+	if err != nil {
+		return volume.Volume{}, fmt.Errorf("unable to create volume %s: %w", options.Name, err)
+	}
+
+	return vol, nil
+}
+
+// VolumesByLabel returns the volumes matching every provided label, limited to the client's namespace when it has a name prefix
+func (d *Docker) VolumesByLabel(labels map[string]string) ([]*volume.Volume, error) {
+	resp, err := d.Client.VolumeList(context.Background(), volume.ListOptions{Filters: d.labelFilters(labels)})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list volumes: %w", err)
+	}
+
+	return resp.Volumes, nil
+}
@@ -0,0 +1,72 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+var buildWarningPatterns = []*regexp.Regexp{
+	// BuildKit plain progress, e.g. "#1 WARN: LegacyKeyValueFormat: ..." and the docker CLI's "WARNING: ..."
+	regexp.MustCompile(`^(?:#\d+ )?WARN(?:ING)?: (.+)$`),
+	// classic builder, e.g. "[Warning] One or more build-args [FOO] were not consumed" and "[WARNING]: Empty continuation line found in: ..."
+	regexp.MustCompile(`^\[(?i:warning)\]:? (.+)$`),
+}
+
+// buildWarning returns the warning reported by a line of build output, if any
+func buildWarning(line []byte) (string, bool) {
+	trimmed := strings.TrimSpace(string(line))
+
+	for _, pattern := range buildWarningPatterns {
+		if matches := pattern.FindStringSubmatch(trimmed); matches != nil {
+			return matches[1], true
+		}
+	}
+
+	return "", false
+}
+
+// warningWriter collects the distinct warnings (e.g. deprecated dockerfile syntax) reported in build output
+type warningWriter struct {
+	lineWriter
+	warnings []string
+}
+
+func newWarningWriter() *warningWriter {
+	w := &warningWriter{warnings: []string{}}
+	w.onLine = w.line
+
+	return w
+}
+
+// line is called with the lineWriter lock held
+func (w *warningWriter) line(line []byte) {
+	if warning, ok := buildWarning(line); ok && !lo.Contains(w.warnings, warning) {
+		w.warnings = append(w.warnings, warning)
+	}
+}
+
+// collected returns the warnings found so far
+func (w *warningWriter) collected() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return append([]string{}, w.warnings...)
+}
@@ -0,0 +1,92 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWarningWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name: "buildkit plain progress",
+			output: `#0 building with "nitric" instance using docker-container driver
+
+#1 [internal] load build definition from Dockerfile
+#1 transferring dockerfile: 142B done
+#1 WARN: LegacyKeyValueFormat: "ENV key=value" should be used instead of legacy "ENV key value" format (line 3)
+#1 WARN: SecretsUsedInArgOrEnv: Do not use ARG or ENV instructions for sensitive data (ARG "API_TOKEN") (line 2)
+#1 DONE 0.0s
+
+#5 [2/2] RUN echo warning: not a builder warning
+#5 DONE 0.2s
+
+ 2 warnings found (use docker --debug to expand):
+ - LegacyKeyValueFormat: "ENV key=value" should be used instead of legacy "ENV key value" format (line 3)
+ - SecretsUsedInArgOrEnv: Do not use ARG or ENV instructions for sensitive data (ARG "API_TOKEN") (line 2)
+`,
+			want: []string{
+				`LegacyKeyValueFormat: "ENV key=value" should be used instead of legacy "ENV key value" format (line 3)`,
+				`SecretsUsedInArgOrEnv: Do not use ARG or ENV instructions for sensitive data (ARG "API_TOKEN") (line 2)`,
+			},
+		},
+		{
+			name: "classic builder",
+			output: "Sending build context to Docker daemon  2.048kB\r\n" +
+				"[WARNING]: Empty continuation line found in:\r\n" +
+				"Step 1/2 : FROM alpine\r\n" +
+				"[Warning] One or more build-args [UNUSED] were not consumed\r\n" +
+				"Successfully built 05455a08881e\r\n",
+			want: []string{
+				"Empty continuation line found in:",
+				"One or more build-args [UNUSED] were not consumed",
+			},
+		},
+		{
+			name:   "duplicate warnings",
+			output: "WARNING: the dockerfile syntax directive \"docker/dockerfile:1\" is not supported by podman and will be ignored\nWARNING: the dockerfile syntax directive \"docker/dockerfile:1\" is not supported by podman and will be ignored\n",
+			want: []string{
+				"the dockerfile syntax directive \"docker/dockerfile:1\" is not supported by podman and will be ignored",
+			},
+		},
+		{
+			name:   "no warnings",
+			output: "#1 [internal] load build definition from Dockerfile\n#1 DONE 0.0s\n",
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWarningWriter()
+
+			if _, err := w.Write([]byte(tt.output)); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, w.collected()); diff != "" {
+				t.Errorf("warnings mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -29,7 +29,6 @@ import (
 	"syscall"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"github.com/samber/lo"
 	"github.com/spf13/afero"
@@ -51,6 +50,9 @@ type Batch struct {
 	buildContext runtime.RuntimeBuildContext
 
 	runCmd string
+
+	// name of the project the batch belongs to, labels its image and containers with docker.StackLabel
+	stackName string
 }
 
 func (s *Batch) GetFilePath() string {
@@ -158,7 +160,7 @@ func (s *Batch) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate,
 		opt(runtimeOptions)
 	}
 
-	dockerClient, err := docker.New()
+	dockerClient, err := docker.Default()
 	if err != nil {
 		return err
 	}
@@ -224,14 +226,29 @@ func (s *Batch) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate,
 		},
 	}
 
+	if err := runtimeOptions.applyHostConfig(hostConfig); err != nil {
+		return err
+	}
+
 	containerConfig := &container.Config{
-		Image: s.Name, // Select an image to use based on the handler
-		Env:   env,
+		Image:  s.Name, // Select an image to use based on the handler
+		Env:    env,
+		Labels: stackLabels(s.stackName),
 		ExposedPorts: nat.PortSet{
 			nat.Port(hostProxyPort): struct{}{},
 		},
 	}
 
+	if err := runtimeOptions.applyContainerConfig(containerConfig); err != nil {
+		return err
+	}
+
+	if runtimeOptions.pullPolicy != "" {
+		if err := dockerClient.PullWithPolicy(containerConfig.Image, runtimeOptions.pullPolicy); err != nil {
+			return err
+		}
+	}
+
 	// Create the container
 	containerId, err := dockerClient.ContainerCreate(
 		containerConfig,
@@ -333,20 +350,24 @@ func (s *Batch) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate,
 			return err
 		case okBody := <-okChan:
 			if okBody.StatusCode != 0 {
-				logOptions := container.LogsOptions{ShowStdout: true, ShowStderr: true, Tail: "20"}
-
-				logReader, err := dockerClient.ContainerLogs(context.Background(), containerId, logOptions)
-				if err != nil {
-					return err
-				}
-
 				// Create a buffer to hold the logs
 				var logs bytes.Buffer
-				if _, err := stdcopy.StdCopy(&logs, &logs, logReader); err != nil {
+				if err := dockerClient.Logs(context.Background(), containerId, docker.LogOptions{Tail: "20"}, &logs, &logs); err != nil {
 					return fmt.Errorf("error reading logs for service %s: %w", s.Name, err)
 				}
 
-				err = fmt.Errorf("service %s exited with non 0 status\n %s", s.Name, logs.String())
+				exitReason := "exited with non 0 status"
+				if status, statusErr := dockerClient.Status(containerId); statusErr == nil {
+					exitReason = status.ExitReason()
+
+					if status.OOMKilled {
+						if hint := dockerClient.ResourceLimitHint(docker.ResourceMemory); hint != "" {
+							exitReason = fmt.Sprintf("%s\n %s", exitReason, hint)
+						}
+					}
+				}
+
+				err = fmt.Errorf("service %s %s\n %s", s.Name, exitReason, logs.String())
 
 				updates <- ServiceRunUpdate{
 					ServiceName: s.Name,
@@ -383,7 +404,7 @@ func (s *Batch) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate,
 
 // FIXME: Duplicate code from service.go
 func (s *Batch) BuildImage(fs afero.Fs, logs io.Writer, useBuilder bool) error {
-	dockerClient, err := docker.New()
+	dockerClient, err := docker.Default()
 	if err != nil {
 		return err
 	}
@@ -398,10 +419,7 @@ func (s *Batch) BuildImage(fs afero.Fs, logs io.Writer, useBuilder bool) error {
 		return fmt.Errorf("unable to create temporary dockerfile for service %s: %w", s.Name, err)
 	}
 
-	if err := afero.WriteFile(fs, tmpDockerFile.Name(), []byte(s.buildContext.DockerfileContents), os.ModePerm); err != nil {
-		return fmt.Errorf("unable to write temporary dockerfile for service %s: %w", s.Name, err)
-	}
-
+	// remove the dockerfile even if it can't be written or the build fails
 	defer func() {
 		tmpDockerFile.Close()
 
@@ -411,6 +429,10 @@ func (s *Batch) BuildImage(fs afero.Fs, logs io.Writer, useBuilder bool) error {
 		}
 	}()
 
+	if err := afero.WriteFile(fs, tmpDockerFile.Name(), []byte(s.buildContext.DockerfileContents), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to write temporary dockerfile for service %s: %w", s.Name, err)
+	}
+
 	// build the docker image
 	err = dockerClient.Build(
 		tmpDockerFile.Name(),
@@ -420,6 +442,7 @@ func (s *Batch) BuildImage(fs afero.Fs, logs io.Writer, useBuilder bool) error {
 		docker.WithExcludes(strings.Split(s.buildContext.IgnoreFileContents, "\n")),
 		docker.WithLogger(logs),
 		docker.WithBuilder(useBuilder),
+		docker.WithLabels(stackLabels(s.stackName)),
 	)
 	if err != nil {
 		return err
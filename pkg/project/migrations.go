@@ -84,7 +84,7 @@ func BuildMigrationImage(fs afero.Fs, dbName string, buildContext *runtime.Runti
 	tempBuildDir := GetTempBuildDir()
 	svcName := migrationImageName(dbName)
 
-	dockerClient, err := docker.New()
+	dockerClient, err := docker.Default()
 	if err != nil {
 		return err
 	}
@@ -99,10 +99,7 @@ func BuildMigrationImage(fs afero.Fs, dbName string, buildContext *runtime.Runti
 		return fmt.Errorf("unable to create temporary dockerfile for service %s: %w", svcName, err)
 	}
 
-	if err := afero.WriteFile(fs, tmpDockerFile.Name(), []byte(buildContext.DockerfileContents), os.ModePerm); err != nil {
-		return fmt.Errorf("unable to write temporary dockerfile for service %s: %w", svcName, err)
-	}
-
+	// remove the dockerfile even if it can't be written or the build fails
 	defer func() {
 		tmpDockerFile.Close()
 
@@ -112,6 +109,10 @@ func BuildMigrationImage(fs afero.Fs, dbName string, buildContext *runtime.Runti
 		}
 	}()
 
+	if err := afero.WriteFile(fs, tmpDockerFile.Name(), []byte(buildContext.DockerfileContents), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to write temporary dockerfile for service %s: %w", svcName, err)
+	}
+
 	// build the docker image
 	err = dockerClient.Build(
 		tmpDockerFile.Name(),
@@ -187,7 +188,7 @@ func BuildMigrationImages(fs afero.Fs, migrationBuildContexts map[string]*runtim
 
 // Run the migrations
 func RunMigration(databaseName string, connectionString string) error {
-	client, err := docker.New()
+	client, err := docker.Default()
 	if err != nil {
 		return err
 	}
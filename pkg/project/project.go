@@ -633,6 +633,7 @@ func fromProjectConfiguration(projectConfig *ProjectConfiguration, localConfig *
 					buildContext: *buildContext,
 					Type:         svc.Type,
 					startCmd:     svc.Start,
+					stackName:    projectConfig.Name,
 				}
 
 				if svc.Type == "" {
@@ -647,6 +648,7 @@ func fromProjectConfiguration(projectConfig *ProjectConfiguration, localConfig *
 					filepath:     relativeFilePath,
 					buildContext: *buildContext,
 					runCmd:       batch.Start,
+					stackName:    projectConfig.Name,
 				}
 
 				batches = append(batches, newBatch)
@@ -25,11 +25,12 @@ import (
 	"os/exec"
 	"path/filepath"
 	goruntime "runtime"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"github.com/samber/lo"
 	"github.com/spf13/afero"
@@ -53,6 +54,9 @@ type Service struct {
 	buildContext runtime.RuntimeBuildContext
 
 	startCmd string
+
+	// name of the project the service belongs to, labels its image and containers with docker.StackLabel
+	stackName string
 }
 
 const tempBuildDir = "./.nitric/build"
@@ -61,6 +65,15 @@ func GetTempBuildDir() string {
 	return tempBuildDir
 }
 
+// stackLabels returns the labels identifying the images and containers of a project's services, none when the project is unknown
+func stackLabels(stackName string) map[string]string {
+	if stackName == "" {
+		return nil
+	}
+
+	return map[string]string{docker.StackLabel: stackName}
+}
+
 func (s *Service) GetFilePath() string {
 	return filepath.Join(s.basedir, s.filepath)
 }
@@ -142,7 +155,7 @@ func NewBuildUpdateWriter(serviceName string, buildUpdateChan chan ServiceBuildU
 }
 
 func (s *Service) BuildImage(fs afero.Fs, logs io.Writer, useBuilder bool) error {
-	dockerClient, err := docker.New()
+	dockerClient, err := docker.Default()
 	if err != nil {
 		return err
 	}
@@ -157,10 +170,7 @@ func (s *Service) BuildImage(fs afero.Fs, logs io.Writer, useBuilder bool) error
 		return fmt.Errorf("unable to create temporary dockerfile for service %s: %w", s.Name, err)
 	}
 
-	if err := afero.WriteFile(fs, tmpDockerFile.Name(), []byte(s.buildContext.DockerfileContents), os.ModePerm); err != nil {
-		return fmt.Errorf("unable to write temporary dockerfile for service %s: %w", s.Name, err)
-	}
-
+	// remove the dockerfile even if it can't be written or the build fails
 	defer func() {
 		tmpDockerFile.Close()
 
@@ -170,6 +180,10 @@ func (s *Service) BuildImage(fs afero.Fs, logs io.Writer, useBuilder bool) error
 		}
 	}()
 
+	if err := afero.WriteFile(fs, tmpDockerFile.Name(), []byte(s.buildContext.DockerfileContents), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to write temporary dockerfile for service %s: %w", s.Name, err)
+	}
+
 	// build the docker image
 	err = dockerClient.Build(
 		tmpDockerFile.Name(),
@@ -179,6 +193,7 @@ func (s *Service) BuildImage(fs afero.Fs, logs io.Writer, useBuilder bool) error
 		docker.WithExcludes(strings.Split(s.buildContext.IgnoreFileContents, "\n")),
 		docker.WithLogger(logs),
 		docker.WithBuilder(useBuilder),
+		docker.WithLabels(stackLabels(s.stackName)),
 	)
 	if err != nil {
 		return err
@@ -192,6 +207,25 @@ type runContainerOptions struct {
 	nitricPort        string
 	nitricEnvironment string
 	envVars           map[string]string
+	dnsServers        []string
+	dnsSearch         []string
+	dnsOptions        []string
+	memoryLimit       int64
+	memoryReservation int64
+	cpuShares         int64
+	entrypoint        []string
+	workingDir        string
+	stopTimeout       *time.Duration
+	stopSignal        string
+	init              bool
+	user              string
+	groupAdd          []string
+	logConfig         *container.LogConfig
+	pullPolicy        docker.PullPolicy
+	readonlyRootfs    bool
+	tmpfsPaths        []string
+	sysctls           map[string]string
+	cgroupParent      string
 }
 
 type RunContainerOption func(*runContainerOptions)
@@ -227,6 +261,252 @@ func WithEnvVars(envVars map[string]string) RunContainerOption {
 	}
 }
 
+// WithDNS - sets the DNS servers, search domains and resolver options for the container.
+// When unset the docker daemon defaults are used.
+func WithDNS(servers, search, options []string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.dnsServers = servers
+		o.dnsSearch = search
+		o.dnsOptions = options
+	}
+}
+
+// WithMemory - sets the hard memory limit and soft memory reservation of the container in bytes, 0 leaves either unset.
+// The reservation allows the container to burst above it while the host has memory to spare.
+func WithMemory(limit, reservation int64) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.memoryLimit = limit
+		o.memoryReservation = reservation
+	}
+}
+
+// WithCPUShares - sets the relative CPU weight of the container, docker's default is 1024
+func WithCPUShares(shares int64) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.cpuShares = shares
+	}
+}
+
+// WithEntrypoint - overrides the image's entrypoint, e.g. []string{"sleep", "infinity"} for debugging.
+// A nil entrypoint keeps the image default, an empty (non-nil) entrypoint resets it so only the command runs.
+func WithEntrypoint(entrypoint []string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.entrypoint = entrypoint
+	}
+}
+
+// WithWorkingDir - sets the working directory commands run from, defaults to the image's working directory
+func WithWorkingDir(dir string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.workingDir = dir
+	}
+}
+
+// WithStopTimeout - sets how long the container is given to exit gracefully when stopped, before it's killed.
+// Docker only accepts whole seconds, sub-second timeouts are rounded up. Defaults to the docker daemon's default (10 seconds).
+func WithStopTimeout(timeout time.Duration) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.stopTimeout = &timeout
+	}
+}
+
+// WithStopSignal - sets the signal sent to the container's main process to stop it, e.g. SIGINT. Defaults to SIGTERM.
+func WithStopSignal(signal string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.stopSignal = signal
+	}
+}
+
+var stopSignalNames = []string{
+	"ABRT", "ALRM", "BUS", "CHLD", "CONT", "FPE", "HUP", "ILL", "INT", "IO", "KILL", "PIPE", "PROF", "PWR",
+	"QUIT", "SEGV", "STKFLT", "STOP", "SYS", "TERM", "TRAP", "TSTP", "TTIN", "TTOU", "URG", "USR1", "USR2",
+	"VTALRM", "WINCH", "XCPU", "XFSZ",
+}
+
+// validateStopSignal - checks the signal is a signal name (with or without the SIG prefix) or number docker will accept
+func validateStopSignal(signal string) error {
+	if num, err := strconv.Atoi(signal); err == nil {
+		if num < 1 || num > 64 {
+			return fmt.Errorf("invalid stop signal %s: signal numbers must be between 1 and 64", signal)
+		}
+
+		return nil
+	}
+
+	if !lo.Contains(stopSignalNames, strings.TrimPrefix(strings.ToUpper(signal), "SIG")) {
+		return fmt.Errorf("invalid stop signal %s", signal)
+	}
+
+	return nil
+}
+
+// WithInit - runs a minimal init process as PID 1 in the container, which forwards signals and reaps zombie processes
+func WithInit(init bool) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.init = init
+	}
+}
+
+// WithUser - runs the container as the user, given as a name or UID, optionally with a primary group in the user:group form
+func WithUser(user string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.user = user
+	}
+}
+
+// WithGroupAdd - adds supplementary groups (names or GIDs) for the container's user, e.g. to access group owned volumes
+func WithGroupAdd(groups []string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.groupAdd = groups
+	}
+}
+
+// WithLogConfig - sets the logging driver of the container and its options, e.g. "json-file" with max-size/max-file rotation,
+// or "none" to discard logs. Defaults to json-file with 10MB x 3 rotation.
+func WithLogConfig(driver string, options map[string]string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.logConfig = &container.LogConfig{
+			Type:   driver,
+			Config: options,
+		}
+	}
+}
+
+// WithPullPolicy - pulls the container image before the container is created, according to the policy.
+// By default no pull is attempted and the image must already exist locally.
+func WithPullPolicy(policy docker.PullPolicy) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.pullPolicy = policy
+	}
+}
+
+// WithReadonlyRootfs - mounts the container's root filesystem read-only, matching hardened production configurations.
+// Paths the service needs to write to (e.g. /tmp) are mounted as tmpfs.
+func WithReadonlyRootfs(writablePaths ...string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.readonlyRootfs = true
+		o.tmpfsPaths = writablePaths
+	}
+}
+
+// WithSysctls - sets namespaced kernel parameters of the container (e.g. net.core.somaxconn), matching tuned production environments.
+// Only sysctls namespaced to the container are supported: the IPC kernel.* parameters, fs.mqueue.* and net.*.
+func WithSysctls(sysctls map[string]string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.sysctls = sysctls
+	}
+}
+
+// namespacedIPCSysctls are the kernel.* sysctls namespaced to a container's IPC namespace
+var namespacedIPCSysctls = []string{
+	"kernel.msgmax", "kernel.msgmnb", "kernel.msgmni", "kernel.sem",
+	"kernel.shmall", "kernel.shmmax", "kernel.shmmni", "kernel.shm_rmid_forced",
+}
+
+// validateSysctl ensures a sysctl is namespaced, docker refuses to set sysctls that would affect the host
+func validateSysctl(name string) error {
+	if lo.Contains(namespacedIPCSysctls, name) || strings.HasPrefix(name, "fs.mqueue.") || strings.HasPrefix(name, "net.") {
+		return nil
+	}
+
+	return fmt.Errorf("sysctl %s is not namespaced and can't be set for a container, only IPC kernel.* parameters, fs.mqueue.* and net.* are supported", name)
+}
+
+// WithCgroupParent - places the container under the provided parent cgroup (e.g. a cgroup managed by a CI runner),
+// so resource accounting of nested container environments includes the container. The daemon's default is used when unset.
+func WithCgroupParent(parent string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.cgroupParent = parent
+	}
+}
+
+// applyContainerConfig - applies the container options that map onto the docker container config
+func (o *runContainerOptions) applyContainerConfig(containerConfig *container.Config) error {
+	if o.entrypoint != nil {
+		if len(o.entrypoint) == 0 {
+			// docker only resets the entrypoint when given a single empty string
+			containerConfig.Entrypoint = []string{""}
+		} else {
+			containerConfig.Entrypoint = o.entrypoint
+		}
+	}
+
+	if o.workingDir != "" {
+		containerConfig.WorkingDir = o.workingDir
+	}
+
+	if o.stopTimeout != nil {
+		containerConfig.StopTimeout = lo.ToPtr(docker.StopTimeoutSeconds(*o.stopTimeout))
+	}
+
+	if o.stopSignal != "" {
+		if err := validateStopSignal(o.stopSignal); err != nil {
+			return err
+		}
+
+		containerConfig.StopSignal = o.stopSignal
+	}
+
+	if o.user != "" {
+		user, group, hasGroup := strings.Cut(o.user, ":")
+		if user == "" || (hasGroup && (group == "" || strings.Contains(group, ":"))) {
+			return fmt.Errorf("invalid container user %s, expected user or user:group", o.user)
+		}
+
+		containerConfig.User = o.user
+	}
+
+	return nil
+}
+
+// applyHostConfig - applies the container options that map onto the docker host config
+func (o *runContainerOptions) applyHostConfig(hostConfig *container.HostConfig) error {
+	if o.memoryLimit > 0 && o.memoryReservation > o.memoryLimit {
+		return fmt.Errorf("memory reservation %d must not exceed the memory limit %d", o.memoryReservation, o.memoryLimit)
+	}
+
+	hostConfig.DNS = o.dnsServers
+	hostConfig.DNSSearch = o.dnsSearch
+	hostConfig.DNSOptions = o.dnsOptions
+	hostConfig.Resources.Memory = o.memoryLimit
+	hostConfig.Resources.MemoryReservation = o.memoryReservation
+	hostConfig.Resources.CPUShares = o.cpuShares
+
+	if o.init {
+		hostConfig.Init = lo.ToPtr(true)
+	}
+
+	hostConfig.GroupAdd = o.groupAdd
+
+	if o.logConfig != nil {
+		hostConfig.LogConfig = *o.logConfig
+	}
+
+	if o.readonlyRootfs {
+		hostConfig.ReadonlyRootfs = true
+
+		if len(o.tmpfsPaths) > 0 {
+			hostConfig.Tmpfs = map[string]string{}
+
+			for _, path := range o.tmpfsPaths {
+				hostConfig.Tmpfs[path] = ""
+			}
+		}
+	}
+
+	for name := range o.sysctls {
+		if err := validateSysctl(name); err != nil {
+			return err
+		}
+	}
+
+	hostConfig.Sysctls = o.sysctls
+
+	hostConfig.CgroupParent = o.cgroupParent
+
+	return nil
+}
+
 type writerFunc func(p []byte) (n int, err error)
 
 func (wf writerFunc) Write(p []byte) (n int, err error) {
@@ -330,7 +610,7 @@ func (s *Service) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate
 		opt(runtimeOptions)
 	}
 
-	dockerClient, err := docker.New()
+	dockerClient, err := docker.Default()
 	if err != nil {
 		return err
 	}
@@ -396,14 +676,29 @@ func (s *Service) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate
 		},
 	}
 
+	if err := runtimeOptions.applyHostConfig(hostConfig); err != nil {
+		return err
+	}
+
 	containerConfig := &container.Config{
-		Image: s.Name, // Select an image to use based on the handler
-		Env:   env,
+		Image:  s.Name, // Select an image to use based on the handler
+		Env:    env,
+		Labels: stackLabels(s.stackName),
 		ExposedPorts: nat.PortSet{
 			nat.Port(hostProxyPort): struct{}{},
 		},
 	}
 
+	if err := runtimeOptions.applyContainerConfig(containerConfig); err != nil {
+		return err
+	}
+
+	if runtimeOptions.pullPolicy != "" {
+		if err := dockerClient.PullWithPolicy(containerConfig.Image, runtimeOptions.pullPolicy); err != nil {
+			return err
+		}
+	}
+
 	// Create the container
 	containerId, err := dockerClient.ContainerCreate(
 		containerConfig,
@@ -507,20 +802,24 @@ func (s *Service) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate
 			return err
 		case okBody := <-okChan:
 			if okBody.StatusCode != 0 {
-				logOptions := container.LogsOptions{ShowStdout: true, ShowStderr: true, Tail: "20"}
-
-				logReader, err := dockerClient.ContainerLogs(context.Background(), containerId, logOptions)
-				if err != nil {
-					return err
-				}
-
 				// Create a buffer to hold the logs
 				var logs bytes.Buffer
-				if _, err := stdcopy.StdCopy(&logs, &logs, logReader); err != nil {
+				if err := dockerClient.Logs(context.Background(), containerId, docker.LogOptions{Tail: "20"}, &logs, &logs); err != nil {
 					return fmt.Errorf("error reading logs for service %s: %w", s.Name, err)
 				}
 
-				err = fmt.Errorf("service %s exited with non 0 status\n %s", s.Name, logs.String())
+				exitReason := "exited with non 0 status"
+				if status, statusErr := dockerClient.Status(containerId); statusErr == nil {
+					exitReason = status.ExitReason()
+
+					if status.OOMKilled {
+						if hint := dockerClient.ResourceLimitHint(docker.ResourceMemory); hint != "" {
+							exitReason = fmt.Sprintf("%s\n %s", exitReason, hint)
+						}
+					}
+				}
+
+				err = fmt.Errorf("service %s %s\n %s", s.Name, exitReason, logs.String())
 
 				updates <- ServiceRunUpdate{
 					ServiceName: s.Name,
@@ -34,11 +34,14 @@ type ProviderImage struct {
 	// unique name/reference for the image - registry-host[:port]/][username/]repository[:tag]
 	imageName string
 
+	// name of the project deployed by the provider, labels the provider container with docker.StackLabel when set
+	stackName string
+
 	containerId string
 }
 
 func (pi *ProviderImage) Install() error {
-	d, err := docker.New()
+	d, err := docker.Default()
 	if err != nil {
 		return err
 	}
@@ -63,7 +66,7 @@ func (pi *ProviderImage) Install() error {
 }
 
 func (pi *ProviderImage) Start(options *StartOptions) (string, error) {
-	client, err := docker.New()
+	client, err := docker.Default()
 	if err != nil {
 		return "", err
 	}
@@ -106,6 +109,10 @@ func (pi *ProviderImage) Start(options *StartOptions) (string, error) {
 		},
 	}
 
+	if pi.stackName != "" {
+		containerConfig.Labels = map[string]string{docker.StackLabel: pi.stackName}
+	}
+
 	if pi.containerId == "" {
 		pi.containerId, err = client.ContainerCreate(containerConfig, hostConfig, nil, "")
 		if err != nil {
@@ -150,7 +157,7 @@ func (wf writerFunc) Write(p []byte) (n int, err error) {
 }
 
 func (pi *ProviderImage) Stop() error {
-	client, err := docker.New()
+	client, err := docker.Default()
 	if err != nil {
 		return fmt.Errorf("error creating Docker client: %w", err)
 	}
@@ -160,7 +167,7 @@ func (pi *ProviderImage) Stop() error {
 }
 
 func (pi *ProviderImage) Uninstall() error {
-	client, err := docker.New()
+	client, err := docker.Default()
 	if err != nil {
 		return err
 	}
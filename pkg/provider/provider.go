@@ -54,6 +54,7 @@ func NewProvider(providerId string, project *project.Project, fs afero.Fs) (Prov
 
 		return &ProviderImage{
 			imageName: dockerUri,
+			stackName: project.Name,
 		}, nil
 	}
 
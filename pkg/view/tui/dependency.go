@@ -19,6 +19,7 @@ package tui
 import (
 	"errors"
 	"os/exec"
+	"sync/atomic"
 
 	"github.com/spf13/cobra"
 )
@@ -61,14 +62,15 @@ func atLeastOne(d ...Dependency) Dependency {
 	}
 }
 
+// availability is cached once detected, these are atomic as checks may run concurrently (e.g. parallel builds)
 var (
-	dockerAvailable       bool
-	dockerBuildxAvailable bool
-	podmanAvailable       bool
+	dockerAvailable       atomic.Bool
+	dockerBuildxAvailable atomic.Bool
+	podmanAvailable       atomic.Bool
 )
 
 func DockerAvailable() error {
-	if dockerAvailable {
+	if dockerAvailable.Load() {
 		return nil
 	}
 
@@ -77,13 +79,13 @@ func DockerAvailable() error {
 		return err
 	}
 
-	dockerAvailable = true
+	dockerAvailable.Store(true)
 
 	return nil
 }
 
 func DockerBuildxAvailable() error {
-	if dockerBuildxAvailable {
+	if dockerBuildxAvailable.Load() {
 		return nil
 	}
 
@@ -92,13 +94,13 @@ func DockerBuildxAvailable() error {
 		return err
 	}
 
-	dockerBuildxAvailable = true
+	dockerBuildxAvailable.Store(true)
 
 	return nil
 }
 
 func PodmanAvailable() error {
-	if podmanAvailable {
+	if podmanAvailable.Load() {
 		return nil
 	}
 
@@ -107,13 +109,13 @@ func PodmanAvailable() error {
 		return err
 	}
 
-	podmanAvailable = true
+	podmanAvailable.Store(true)
 
 	return nil
 }
 
 func RequireDocker() *DependencyError {
-	if dockerAvailable {
+	if dockerAvailable.Load() {
 		return nil
 	}
 
@@ -137,13 +139,13 @@ func RequireDocker() *DependencyError {
 		return &depErr
 	}
 
-	dockerBuildxAvailable = true
+	dockerBuildxAvailable.Store(true)
 
 	return nil
 }
 
 func RequirePodman() *DependencyError {
-	if podmanAvailable {
+	if podmanAvailable.Load() {
 		return nil
 	}
 
@@ -157,7 +159,7 @@ func RequirePodman() *DependencyError {
 		return &depErr
 	}
 
-	podmanAvailable = true
+	podmanAvailable.Store(true)
 
 	return nil
 }